@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JJApplication/Themis/internal/port"
+	"github.com/JJApplication/Themis/internal/storage"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+)
+
+// raftLogFile Raft日志与任期/投票状态持久化到cfg.SnapshotDir下的bolt文件名，
+// 与快照共享同一个目录，避免新增配置项
+const raftLogFile = "raft-log.db"
+
+// applyTimeout Raft日志提交的默认超时时间
+const applyTimeout = 5 * time.Second
+
+// Cluster 基于Raft的端口状态复制集群
+type Cluster struct {
+	raft     *raft.Raft
+	fsm      *FSM
+	raftLogs *raftboltdb.BoltStore
+}
+
+// New 创建并启动一个Raft集群节点。Peers为空时以单节点方式自举（bootstrap）
+func New(cfg Config, appStorage storage.Store, portManager *port.PortManager) (*Cluster, error) {
+	if cfg.NodeID == "" {
+		return nil, fmt.Errorf("集群节点ID不能为空")
+	}
+	if cfg.BindAddr == "" {
+		return nil, fmt.Errorf("集群监听地址不能为空")
+	}
+	if err := os.MkdirAll(cfg.SnapshotDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建集群快照目录失败: %v", err)
+	}
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(cfg.NodeID)
+
+	addr, err := net.ResolveTCPAddr("tcp", cfg.BindAddr)
+	if err != nil {
+		return nil, fmt.Errorf("解析集群监听地址失败: %v", err)
+	}
+	transport, err := raft.NewTCPTransport(cfg.BindAddr, addr, 3, 10*time.Second, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("创建集群传输层失败: %v", err)
+	}
+
+	snapshotStore, err := raft.NewFileSnapshotStore(cfg.SnapshotDir, 2, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("创建集群快照存储失败: %v", err)
+	}
+
+	// 日志与任期/投票状态落盘到bolt文件，避免重启后丢失已提交但尚未生成快照的日志条目
+	raftLogs, err := raftboltdb.NewBoltStore(filepath.Join(cfg.SnapshotDir, raftLogFile))
+	if err != nil {
+		return nil, fmt.Errorf("创建集群持久化日志存储失败: %v", err)
+	}
+
+	fsm := NewFSM(appStorage, portManager)
+
+	r, err := raft.NewRaft(raftConfig, fsm, raftLogs, raftLogs, snapshotStore, transport)
+	if err != nil {
+		return nil, fmt.Errorf("初始化Raft节点失败: %v", err)
+	}
+
+	if len(cfg.Peers) == 0 {
+		servers := []raft.Server{{ID: raftConfig.LocalID, Address: transport.LocalAddr()}}
+		r.BootstrapCluster(raft.Configuration{Servers: servers})
+	}
+
+	return &Cluster{raft: r, fsm: fsm, raftLogs: raftLogs}, nil
+}
+
+// Apply 提交一条命令到Raft日志，仅在leader节点上可成功执行
+func (c *Cluster) Apply(cmd Command) (ApplyResult, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return ApplyResult{}, fmt.Errorf("序列化集群命令失败: %v", err)
+	}
+
+	future := c.raft.Apply(data, applyTimeout)
+	if err := future.Error(); err != nil {
+		return ApplyResult{}, err
+	}
+
+	result, _ := future.Response().(ApplyResult)
+	return result, result.Err
+}
+
+// Join 将一个新节点加入集群，只能在leader上调用
+func (c *Cluster) Join(nodeID, addr string) error {
+	future := c.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	return future.Error()
+}
+
+// Leave 将一个节点移出集群，只能在leader上调用
+func (c *Cluster) Leave(nodeID string) error {
+	future := c.raft.RemoveServer(raft.ServerID(nodeID), 0, 0)
+	return future.Error()
+}
+
+// Leader 返回当前leader的地址，没有leader时返回空字符串
+func (c *Cluster) Leader() string {
+	addr, _ := c.raft.LeaderWithID()
+	return string(addr)
+}
+
+// IsLeader 当前节点是否为leader
+func (c *Cluster) IsLeader() bool {
+	return c.raft.State() == raft.Leader
+}
+
+// Shutdown 关闭Raft节点并释放持久化日志存储的文件句柄
+func (c *Cluster) Shutdown() error {
+	if err := c.raft.Shutdown().Error(); err != nil {
+		return err
+	}
+	return c.raftLogs.Close()
+}
+
+// Config 集群配置，对应config.ClusterConfig
+type Config struct {
+	NodeID      string
+	BindAddr    string
+	Peers       []string
+	SnapshotDir string
+}
@@ -0,0 +1,244 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/JJApplication/Themis/internal/port"
+	"github.com/JJApplication/Themis/internal/storage"
+	"github.com/hashicorp/raft"
+)
+
+// Op 日志命令类型
+type Op string
+
+const (
+	OpSetAppPort    Op = "set_app_port"
+	OpDeleteAppPort Op = "delete_app_port"
+	OpAcquirePort   Op = "acquire_port"
+	OpReleasePort   Op = "release_port"
+	OpAcquireLease  Op = "acquire_lease"
+	OpRenewLease    Op = "renew_lease"
+	OpReleaseLease  Op = "release_lease"
+	OpCreatePool    Op = "create_pool"
+	OpDeletePool    Op = "delete_pool"
+)
+
+// Command 一条被Raft日志复制的端口状态变更命令。所有需要随机数或本地时钟的字段
+// （如Port、LeaseID、ExpiresAt）必须由提交命令的leader提前决定好再提交，
+// FSM.Apply只负责确定性地回放这些已经决定好的值，不能在Apply内部重新计算，
+// 否则各副本会算出不同的结果。
+type Command struct {
+	Op        Op     `json:"op"`
+	AppName   string `json:"app_name,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	Pool      string `json:"pool,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+	LeaseID   string `json:"lease_id,omitempty"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	MinPort   int    `json:"min_port,omitempty"`
+	MaxPort   int    `json:"max_port,omitempty"`
+	Quota     int    `json:"quota,omitempty"`
+}
+
+// ApplyResult Apply后返回给提交者的结果
+type ApplyResult struct {
+	Port      int
+	LeaseID   string
+	ExpiresAt int64
+	Err       error
+}
+
+// FSM 将PortManager和storage.Store包装为Raft可复制的状态机
+type FSM struct {
+	storage     storage.Store
+	portManager *port.PortManager
+}
+
+// NewFSM 创建新的状态机
+func NewFSM(storage storage.Store, portManager *port.PortManager) *FSM {
+	return &FSM{storage: storage, portManager: portManager}
+}
+
+// Apply 对一条已提交的日志应用到本地状态，所有集群节点执行相同的命令序列
+func (f *FSM) Apply(log *raft.Log) interface{} {
+	var cmd Command
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return ApplyResult{Err: fmt.Errorf("解析集群日志失败: %v", err)}
+	}
+
+	switch cmd.Op {
+	case OpSetAppPort:
+		return ApplyResult{Err: f.storage.SetAppPort(cmd.AppName, cmd.Port)}
+	case OpDeleteAppPort:
+		if p, err := f.storage.GetAppPort(cmd.AppName); err == nil {
+			f.portManager.ReleasePort(p)
+		}
+		return ApplyResult{Err: f.storage.DeleteAppPort(cmd.AppName)}
+	case OpAcquirePort:
+		// cmd.Port已由leader通过PeekRandomPort/PeekRandomPortFromPool提前选定，
+		// 这里只需在每个副本上确定性地标记占用并重新校验冲突/配额，不再自行随机选取端口。
+		if err := f.portManager.MarkPortUsed(cmd.Port, cmd.Pool, cmd.Tenant); err != nil {
+			return ApplyResult{Err: err}
+		}
+		if cmd.AppName != "" {
+			if err := f.storage.SetAppPort(cmd.AppName, cmd.Port); err != nil {
+				f.portManager.ReleasePort(cmd.Port)
+				return ApplyResult{Err: err}
+			}
+		}
+		return ApplyResult{Port: cmd.Port}
+	case OpReleasePort:
+		f.portManager.ReleasePort(cmd.Port)
+		return ApplyResult{}
+	case OpAcquireLease:
+		leaseStore, ok := f.storage.(storage.LeaseStore)
+		if !ok {
+			return ApplyResult{Err: fmt.Errorf("当前存储后端不支持端口租约功能")}
+		}
+		// cmd.Port、cmd.LeaseID、cmd.ExpiresAt均已由leader提前决定好，Apply只负责回放
+		if err := f.portManager.MarkPortUsed(cmd.Port, "", ""); err != nil {
+			return ApplyResult{Err: err}
+		}
+		record := storage.LeaseRecord{LeaseID: cmd.LeaseID, AppName: cmd.AppName, Port: cmd.Port, ExpiresAt: cmd.ExpiresAt}
+		if err := leaseStore.CreateLeaseRecord(record); err != nil {
+			f.portManager.ReleasePort(cmd.Port)
+			return ApplyResult{Err: err}
+		}
+		return ApplyResult{Port: cmd.Port, LeaseID: cmd.LeaseID, ExpiresAt: cmd.ExpiresAt}
+	case OpRenewLease:
+		leaseStore, ok := f.storage.(storage.LeaseStore)
+		if !ok {
+			return ApplyResult{Err: fmt.Errorf("当前存储后端不支持端口租约功能")}
+		}
+		// cmd.ExpiresAt已是leader提前算好的绝对过期时间戳
+		if err := leaseStore.RenewLeaseTo(cmd.LeaseID, cmd.ExpiresAt); err != nil {
+			return ApplyResult{Err: err}
+		}
+		return ApplyResult{LeaseID: cmd.LeaseID, ExpiresAt: cmd.ExpiresAt}
+	case OpReleaseLease:
+		leaseStore, ok := f.storage.(storage.LeaseStore)
+		if !ok {
+			return ApplyResult{Err: fmt.Errorf("当前存储后端不支持端口租约功能")}
+		}
+		record, err := leaseStore.ReleaseLease(cmd.LeaseID)
+		if err != nil {
+			return ApplyResult{Err: err}
+		}
+		f.portManager.ReleasePort(record.Port)
+		return ApplyResult{}
+	case OpCreatePool:
+		if err := f.portManager.CreatePool(cmd.Pool, cmd.MinPort, cmd.MaxPort, cmd.Quota); err != nil {
+			return ApplyResult{Err: err}
+		}
+		if poolStore, ok := f.storage.(storage.PoolStore); ok {
+			record := storage.PoolRecord{Name: cmd.Pool, MinPort: cmd.MinPort, MaxPort: cmd.MaxPort, Quota: cmd.Quota}
+			if err := poolStore.SetPool(record); err != nil {
+				return ApplyResult{Err: err}
+			}
+		}
+		return ApplyResult{}
+	case OpDeletePool:
+		if err := f.portManager.DeletePool(cmd.Pool); err != nil {
+			return ApplyResult{Err: err}
+		}
+		if poolStore, ok := f.storage.(storage.PoolStore); ok {
+			if err := poolStore.DeletePool(cmd.Pool); err != nil {
+				return ApplyResult{Err: err}
+			}
+		}
+		return ApplyResult{}
+	default:
+		return ApplyResult{Err: fmt.Errorf("未知的集群命令: %s", cmd.Op)}
+	}
+}
+
+// Snapshot 生成一份可供新节点恢复的快照，格式与现有的JSON PortData兼容。
+// Leases和Pools仅在存储后端支持LeaseStore/PoolStore时才会被捕获，否则随AppPorts一同缺省为空，
+// 避免新加入的节点或日志压缩后重启的节点丢失这部分状态。
+func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
+	snap := &fsmSnapshot{appPorts: f.storage.GetAllApps()}
+
+	if leaseStore, ok := f.storage.(storage.LeaseStore); ok {
+		snap.leases = leaseStore.ListLeases()
+	}
+	if poolStore, ok := f.storage.(storage.PoolStore); ok {
+		snap.pools = poolStore.ListPools()
+	}
+	return snap, nil
+}
+
+// Restore 从快照恢复状态机，用于新节点加入或节点重启后回放。Leases和Pools同样只在
+// 存储后端支持对应接口时才会被回放，并同步重建PortManager里的pool定义和租约端口占用。
+// 其中pool定义的重建方式与Start()一致；Start()本身不会把已持久化租约的端口标记为占用。
+func (f *FSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+
+	var data storage.PortData
+	if err := json.NewDecoder(rc).Decode(&data); err != nil {
+		return fmt.Errorf("解析集群快照失败: %v", err)
+	}
+
+	for appName, p := range data.AppPorts {
+		if err := f.storage.SetAppPort(appName, p); err != nil {
+			return err
+		}
+	}
+
+	if poolStore, ok := f.storage.(storage.PoolStore); ok {
+		for _, p := range data.Pools {
+			if err := f.portManager.CreatePool(p.Name, p.MinPort, p.MaxPort, p.Quota); err != nil {
+				return err
+			}
+			if err := poolStore.SetPool(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if leaseStore, ok := f.storage.(storage.LeaseStore); ok {
+		for _, l := range data.Leases {
+			if err := f.portManager.MarkPortUsed(l.Port, "", ""); err != nil {
+				return err
+			}
+			if err := leaseStore.CreateLeaseRecord(l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fsmSnapshot 复用现有的JSON PortData格式持久化快照内容
+type fsmSnapshot struct {
+	appPorts map[string]int
+	leases   []storage.LeaseRecord
+	pools    []storage.PoolRecord
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	data := storage.PortData{AppPorts: s.appPorts, Version: "1.2"}
+
+	if len(s.leases) > 0 {
+		data.Leases = make(map[string]storage.LeaseRecord, len(s.leases))
+		for _, l := range s.leases {
+			data.Leases[l.LeaseID] = l
+		}
+	}
+	if len(s.pools) > 0 {
+		data.Pools = make(map[string]storage.PoolRecord, len(s.pools))
+		for _, p := range s.pools {
+			data.Pools[p.Name] = p
+		}
+	}
+
+	encoder := json.NewEncoder(sink)
+	if err := encoder.Encode(data); err != nil {
+		sink.Cancel()
+		return fmt.Errorf("写入集群快照失败: %v", err)
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
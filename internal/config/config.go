@@ -15,6 +15,10 @@ type Config struct {
 	Port PortConfig `json:"port"`
 	// 存储配置
 	Storage StorageConfig `json:"storage"`
+	// 健康探测配置
+	Health HealthConfig `json:"health"`
+	// 集群配置
+	Cluster ClusterConfig `json:"cluster"`
 }
 
 // ServerConfig 服务器配置
@@ -33,8 +37,29 @@ type PortConfig struct {
 
 // StorageConfig 存储配置
 type StorageConfig struct {
-	DataFile     string `json:"data_file"`     // 数据文件路径
-	SyncInterval int    `json:"sync_interval"` // 同步间隔（秒）
+	Driver        string   `json:"driver"`         // 存储后端："json"、"bolt"或"etcd"，默认为"json"
+	DataFile      string   `json:"data_file"`      // JSON文件路径（driver为"json"时使用）
+	SyncInterval  int      `json:"sync_interval"`  // 同步间隔（秒，driver为"json"时用于定期落盘；driver为"json"或"bolt"时同时作为租约回收的轮询间隔）
+	BoltPath      string   `json:"bolt_path"`      // BoltDB文件路径（driver为"bolt"时使用）
+	EtcdEndpoints []string `json:"etcd_endpoints"` // etcd集群地址列表（driver为"etcd"时使用）
+}
+
+// HealthConfig 健康探测配置
+type HealthConfig struct {
+	ProbeInterval    int      `json:"probe_interval"`    // 探测间隔（秒）
+	Timeout          int      `json:"timeout_ms"`        // 单次探测超时（毫秒）
+	FailureThreshold int      `json:"failure_threshold"` // 连续失败多少次标记为UNHEALTHY
+	Protocols        []string `json:"protocols"`         // 探测协议集合："tcp"、"udp"
+	AutoRelease      bool     `json:"auto_release"`      // 标记为UNHEALTHY后是否自动归还端口
+}
+
+// ClusterConfig 集群配置，用于以高可用模式运行多个Themis实例
+type ClusterConfig struct {
+	Enabled     bool     `json:"enabled"`      // 是否启用集群模式
+	NodeID      string   `json:"node_id"`      // 本节点在集群中的唯一ID
+	BindAddr    string   `json:"bind_addr"`    // Raft通信监听地址
+	Peers       []string `json:"peers"`        // 对等节点地址列表；非空时本节点不自举，列表中的地址不会被主动连接，需由leader通过JoinCluster加入本节点
+	SnapshotDir string   `json:"snapshot_dir"` // Raft快照存储目录
 }
 
 // DefaultConfig 返回默认配置
@@ -51,9 +76,20 @@ func DefaultConfig() *Config {
 			MaxPort: 20000,
 		},
 		Storage: StorageConfig{
+			Driver:       "json",
 			DataFile:     "./data/ports.json",
 			SyncInterval: 60, // 60秒
 		},
+		Health: HealthConfig{
+			ProbeInterval:    10,
+			Timeout:          500,
+			FailureThreshold: 3,
+			Protocols:        []string{"tcp"},
+			AutoRelease:      false,
+		},
+		Cluster: ClusterConfig{
+			Enabled: false,
+		},
 	}
 }
 
@@ -131,11 +167,53 @@ func (c *Config) Validate() error {
 	}
 
 	// 验证存储配置
-	if c.Storage.DataFile == "" {
-		return fmt.Errorf("数据文件路径不能为空")
+	switch c.Storage.Driver {
+	case "", "json":
+		if c.Storage.DataFile == "" {
+			return fmt.Errorf("数据文件路径不能为空")
+		}
+		if c.Storage.SyncInterval <= 0 {
+			return fmt.Errorf("同步间隔必须大于0")
+		}
+	case "bolt":
+		if c.Storage.BoltPath == "" {
+			return fmt.Errorf("BoltDB文件路径不能为空")
+		}
+	case "etcd":
+		if len(c.Storage.EtcdEndpoints) == 0 {
+			return fmt.Errorf("etcd集群地址列表不能为空")
+		}
+	default:
+		return fmt.Errorf("无效的存储后端: %s，必须是 'json'、'bolt' 或 'etcd'", c.Storage.Driver)
 	}
-	if c.Storage.SyncInterval <= 0 {
-		return fmt.Errorf("同步间隔必须大于0")
+
+	// 验证健康探测配置
+	if c.Health.ProbeInterval <= 0 {
+		return fmt.Errorf("健康探测间隔必须大于0")
+	}
+	if c.Health.Timeout <= 0 {
+		return fmt.Errorf("健康探测超时必须大于0")
+	}
+	if c.Health.FailureThreshold <= 0 {
+		return fmt.Errorf("健康探测失败阈值必须大于0")
+	}
+	for _, protocol := range c.Health.Protocols {
+		if protocol != "tcp" && protocol != "udp" {
+			return fmt.Errorf("无效的健康探测协议: %s，必须是 'tcp' 或 'udp'", protocol)
+		}
+	}
+
+	// 验证集群配置
+	if c.Cluster.Enabled {
+		if c.Cluster.NodeID == "" {
+			return fmt.Errorf("集群节点ID不能为空")
+		}
+		if c.Cluster.BindAddr == "" {
+			return fmt.Errorf("集群监听地址不能为空")
+		}
+		if c.Cluster.SnapshotDir == "" {
+			return fmt.Errorf("集群快照目录不能为空")
+		}
 	}
 
 	return nil
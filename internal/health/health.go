@@ -0,0 +1,287 @@
+package health
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/JJApplication/Themis/internal/cluster"
+	"github.com/JJApplication/Themis/internal/port"
+	"github.com/JJApplication/Themis/internal/storage"
+)
+
+// Status 端口健康状态
+type Status string
+
+const (
+	StatusUnknown   Status = "UNKNOWN"   // 尚未探测成功过，且连续失败次数未达到阈值
+	StatusHealthy   Status = "HEALTHY"   // 端口正常监听
+	StatusUnhealthy Status = "UNHEALTHY" // 连续探测失败次数达到阈值
+)
+
+// AppHealth 某个APP端口的健康状态
+type AppHealth struct {
+	AppName             string    // APP名称
+	Port                int       // 端口号
+	Status              Status    // 当前状态
+	ConsecutiveFailures int       // 连续失败次数
+	LastCheck           time.Time // 最近一次探测时间
+}
+
+// Event 健康状态变化事件
+type Event struct {
+	AppName   string
+	Port      int
+	Status    Status
+	Timestamp time.Time
+}
+
+// Config 健康探测配置
+type Config struct {
+	ProbeInterval    time.Duration // 探测间隔
+	Timeout          time.Duration // 单次探测超时
+	FailureThreshold int           // 连续失败多少次标记为UNHEALTHY
+	Protocols        []string      // 探测协议集合："tcp"、"udp"
+	AutoRelease      bool          // 标记为UNHEALTHY后是否自动归还端口
+}
+
+// Checker 端口存活探测器
+type Checker struct {
+	mu          sync.RWMutex
+	cfg         Config
+	storage     storage.Store
+	portManager *port.PortManager
+	cluster     *cluster.Cluster // 集群（未启用集群模式时为nil），由SetCluster在集群节点启动后设置
+	statuses    map[string]*AppHealth
+
+	subMu       sync.Mutex
+	subscribers map[chan Event]struct{}
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewChecker 创建新的端口存活探测器
+func NewChecker(cfg Config, storage storage.Store, portManager *port.PortManager) *Checker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if len(cfg.Protocols) == 0 {
+		cfg.Protocols = []string{"tcp"}
+	}
+
+	return &Checker{
+		cfg:         cfg,
+		storage:     storage,
+		portManager: portManager,
+		statuses:    make(map[string]*AppHealth),
+		subscribers: make(map[chan Event]struct{}),
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// SetCluster 绑定集群实例，由server.Start()在集群节点启动成功后调用。绑定后，
+// 自动释放只在leader节点上通过cluster.Apply执行，避免每个副本各自直接调用
+// PortManager造成状态分叉。
+func (c *Checker) SetCluster(cl *cluster.Cluster) {
+	c.mu.Lock()
+	c.cluster = cl
+	c.mu.Unlock()
+}
+
+// Start 启动周期性探测协程
+func (c *Checker) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.cfg.ProbeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.checkAll()
+			case <-c.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止探测协程
+func (c *Checker) Stop() {
+	close(c.stopChan)
+	c.wg.Wait()
+
+	c.subMu.Lock()
+	for ch := range c.subscribers {
+		close(ch)
+	}
+	c.subscribers = make(map[chan Event]struct{})
+	c.subMu.Unlock()
+}
+
+// checkAll 对当前已注册的APP端口逐一探测
+func (c *Checker) checkAll() {
+	for appName, appPort := range c.storage.GetAllApps() {
+		c.checkOne(appName, appPort)
+	}
+}
+
+// checkOne 探测单个APP端口，更新健康状态并在状态变化时广播事件
+func (c *Checker) checkOne(appName string, appPort int) {
+	alive := c.probeWithRetry(appPort)
+
+	c.mu.Lock()
+	health, exists := c.statuses[appName]
+	if !exists {
+		health = &AppHealth{AppName: appName, Port: appPort, Status: StatusUnknown}
+		c.statuses[appName] = health
+	}
+	health.Port = appPort
+	health.LastCheck = time.Now()
+
+	prevStatus := health.Status
+	if alive {
+		health.ConsecutiveFailures = 0
+		health.Status = StatusHealthy
+	} else {
+		health.ConsecutiveFailures++
+		if health.ConsecutiveFailures >= c.cfg.FailureThreshold {
+			health.Status = StatusUnhealthy
+		}
+	}
+	newStatus := health.Status
+	c.mu.Unlock()
+
+	if newStatus == prevStatus {
+		return
+	}
+
+	c.broadcast(Event{AppName: appName, Port: appPort, Status: newStatus, Timestamp: time.Now()})
+
+	if newStatus == StatusUnhealthy && c.cfg.AutoRelease {
+		c.autoRelease(appName, appPort)
+	}
+}
+
+// autoRelease 自动归还一个被判定为UNHEALTHY的端口。集群模式下只在leader节点上
+// 通过cluster.Apply(OpDeleteAppPort)执行，确保端口释放和APP映射删除在所有副本上
+// 以同一条Raft日志确定性地回放；非leader节点什么都不做，等待该日志从leader复制过来。
+func (c *Checker) autoRelease(appName string, appPort int) {
+	c.mu.RLock()
+	cl := c.cluster
+	c.mu.RUnlock()
+
+	if cl != nil {
+		if !cl.IsLeader() {
+			return
+		}
+		if _, err := cl.Apply(cluster.Command{Op: cluster.OpDeleteAppPort, AppName: appName}); err != nil {
+			fmt.Printf("集群自动释放端口 %d 失败: %v\n", appPort, err)
+		}
+		return
+	}
+
+	c.portManager.ReleasePort(appPort)
+	// 同步删除存储中的APP端口映射，否则GetAppPort仍会返回这个已经被重新分配给
+	// 其他APP的端口号，造成两个APP指向同一端口却检测不到冲突
+	if err := c.storage.DeleteAppPort(appName); err != nil {
+		fmt.Printf("自动释放端口 %d 后删除APP '%s' 的存储记录失败: %v\n", appPort, appName, err)
+	}
+}
+
+// probeWithRetry 按配置的协议集合逐一探测端口，每个协议失败后带退避重试，最多尝试3次；
+// 任一协议的3次尝试全部失败即算本轮探测失败，所有协议都探测成功才算本轮成功
+func (c *Checker) probeWithRetry(appPort int) bool {
+	for _, protocol := range c.cfg.Protocols {
+		backoff := c.cfg.Timeout / 4
+		if backoff <= 0 {
+			backoff = 50 * time.Millisecond
+		}
+
+		ok := false
+		for attempt := 0; attempt < 3; attempt++ {
+			if dial(protocol, appPort, c.cfg.Timeout) {
+				ok = true
+				break
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// dial 尝试连接指定协议和端口，用于验证端口背后是否有进程真正在监听
+func dial(protocol string, port int, timeout time.Duration) bool {
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	switch protocol {
+	case "udp":
+		conn, err := net.DialTimeout("udp", addr, timeout)
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		// UDP无连接，写入一个空字节探测对端是否存在（不保证100%准确）
+		_, err = conn.Write([]byte{0})
+		return err == nil
+	default:
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		return true
+	}
+}
+
+// broadcast 将事件推送给所有订阅者
+func (c *Checker) broadcast(event Event) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for ch := range c.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者消费过慢，丢弃事件避免阻塞探测协程
+		}
+	}
+}
+
+// Subscribe 订阅健康状态变化事件，返回的cancel函数用于取消订阅
+func (c *Checker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	c.subMu.Lock()
+	c.subscribers[ch] = struct{}{}
+	c.subMu.Unlock()
+
+	cancel := func() {
+		c.subMu.Lock()
+		if _, ok := c.subscribers[ch]; ok {
+			delete(c.subscribers, ch)
+			close(ch)
+		}
+		c.subMu.Unlock()
+	}
+
+	return ch, cancel
+}
+
+// GetAppHealth 获取某个APP的健康状态
+func (c *Checker) GetAppHealth(appName string) (AppHealth, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	health, exists := c.statuses[appName]
+	if !exists {
+		return AppHealth{}, fmt.Errorf("APP '%s' 尚无健康状态数据", appName)
+	}
+	return *health, nil
+}
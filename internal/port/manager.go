@@ -1,28 +1,215 @@
 package port
 
 import (
+	"errors"
 	"fmt"
+	"math/bits"
 	"math/rand"
 	"net"
 	"sync"
 	"time"
 )
 
+// ErrQuotaExceeded 表示调用方在某个pool中已达到端口配额上限，客户端可据此改为向其他pool申请
+var ErrQuotaExceeded = errors.New("已达到端口配额上限")
+
+// Pool 从[MinPort, MaxPort]中划分出的命名端口子区间，例如 web: 10000-10999
+type Pool struct {
+	Name    string // pool名称
+	MinPort int    // 子区间最小端口号
+	MaxPort int    // 子区间最大端口号
+	Quota   int    // 单个租户在该pool中最多可持有的端口数，0表示不限制
+}
+
+// tenantPortKey 记录一个被占用端口归属的pool和租户，用于释放时归还配额
+type tenantPortKey struct {
+	pool   string
+	tenant string
+}
+
 // PortManager 端口管理器
 type PortManager struct {
 	mu        sync.RWMutex
-	minPort   int          // 最小端口号
-	maxPort   int          // 最大端口号
-	usedPorts map[int]bool // 已使用的端口
+	minPort   int    // 最小端口号
+	maxPort   int    // 最大端口号
+	bitmap    []uint64 // 已使用端口位图，第i位对应端口minPort+i；超出[minPort,maxPort]的padding位恒为已使用
+	freeCount int    // 位图中当前空闲的端口数
+	rng       *rand.Rand
+	pools     map[string]*Pool           // pool名称到定义的映射
+	poolUsage map[string]map[string]int // pool名称 -> 租户 -> 已持有端口数
+	portOwner map[int]tenantPortKey      // 端口 -> 归属的(pool, 租户)
 }
 
 // NewPortManager 创建新的端口管理器
 func NewPortManager(minPort, maxPort int) *PortManager {
-	return &PortManager{
+	size := maxPort - minPort + 1
+	words := (size + 63) / 64
+
+	pm := &PortManager{
 		minPort:   minPort,
 		maxPort:   maxPort,
-		usedPorts: make(map[int]bool),
+		bitmap:    make([]uint64, words),
+		freeCount: size,
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		pools:     make(map[string]*Pool),
+		poolUsage: make(map[string]map[string]int),
+		portOwner: make(map[int]tenantPortKey),
+	}
+
+	// 最后一个word中超出size的padding位永久标记为已使用，避免扫描越界端口
+	if validInLast := size - (words-1)*64; validInLast < 64 && words > 0 {
+		for i := validInLast; i < 64; i++ {
+			pm.bitmap[words-1] |= 1 << uint(i)
+		}
+	}
+
+	return pm
+}
+
+// isFreeLocked 判断位图中第idx位对应的端口是否空闲，调用方需持有pm.mu
+func (pm *PortManager) isFreeLocked(idx int) bool {
+	return pm.bitmap[idx/64]&(1<<uint(idx%64)) == 0
+}
+
+// setBitLocked 将第idx位标记为已使用，调用方需持有pm.mu写锁
+func (pm *PortManager) setBitLocked(idx int) {
+	pm.bitmap[idx/64] |= 1 << uint(idx%64)
+}
+
+// clearBitLocked 将第idx位标记为空闲，调用方需持有pm.mu写锁
+func (pm *PortManager) clearBitLocked(idx int) {
+	pm.bitmap[idx/64] &^= 1 << uint(idx%64)
+}
+
+// nextFreeBitLocked 从start位（含）开始循环扫描位图，返回第一个空闲位的下标；整个位图均已占满时返回-1。
+// 调用方需持有pm.mu。借助bits.TrailingZeros64对取反后的word做位扫描，最坏情况为O(len(bitmap))次word级别比较。
+func (pm *PortManager) nextFreeBitLocked(start int) int {
+	words := len(pm.bitmap)
+	if words == 0 {
+		return -1
+	}
+	// 调用方可能传入idx+1越过位图末尾，回绕到位图开头
+	start %= words * 64
+	wordStart := start / 64
+	bitStart := uint(start % 64)
+
+	// 第一遍：起始word中从bitStart开始的高位部分
+	word := pm.bitmap[wordStart]
+	free := (^word) &^ ((uint64(1) << bitStart) - 1)
+	if free != 0 {
+		return wordStart*64 + bits.TrailingZeros64(free)
+	}
+
+	// 中间：其余word按环形顺序扫描
+	for w := 1; w < words; w++ {
+		wi := (wordStart + w) % words
+		if free := ^pm.bitmap[wi]; free != 0 {
+			return wi*64 + bits.TrailingZeros64(free)
+		}
+	}
+
+	// 最后：回绕到起始word中bitStart之前的低位部分
+	if bitStart > 0 {
+		free := (^word) & ((uint64(1) << bitStart) - 1)
+		if free != 0 {
+			return wordStart*64 + bits.TrailingZeros64(free)
+		}
+	}
+
+	return -1
+}
+
+// scanFreeForwardLocked 在位图[lo, hi]闭区间内从start开始向后（不回绕）查找第一个空闲位，
+// 找不到时返回-1。调用方需持有pm.mu。通过逐word使用bits.TrailingZeros64定位，
+// 仅在区间的首尾word上做位掩码以限定在[lo,hi]内，其余word整word扫描。
+func (pm *PortManager) scanFreeForwardLocked(start, lo, hi int) int {
+	if start < lo {
+		start = lo
+	}
+	if start > hi {
+		return -1
+	}
+
+	wordStart := start / 64
+	wordEnd := hi / 64
+
+	for wi := wordStart; wi <= wordEnd; wi++ {
+		free := ^pm.bitmap[wi]
+
+		wordLo := wi * 64
+		if start > wordLo {
+			// 屏蔽掉本word中低于区间下界的位
+			free &^= (uint64(1) << uint(start-wordLo)) - 1
+		}
+		wordHi := wordLo + 63
+		if hi < wordHi {
+			// 屏蔽掉本word中高于区间上界的位
+			free &= (uint64(1) << uint(hi-wordLo+1)) - 1
+		}
+
+		if free != 0 {
+			return wordLo + bits.TrailingZeros64(free)
+		}
+		start = wordLo + 64
+	}
+
+	return -1
+}
+
+// nextFreeBitInRangeLocked 在位图[lo, hi]闭区间内循环扫描，从start位（含）开始查找第一个空闲位；
+// 区间已占满时返回-1。调用方需持有pm.mu。用于在某个pool的端口子区间内定位空闲端口。
+func (pm *PortManager) nextFreeBitInRangeLocked(start, lo, hi int) int {
+	if lo > hi {
+		return -1
+	}
+	if start < lo || start > hi {
+		start = lo
+	}
+
+	if idx := pm.scanFreeForwardLocked(start, lo, hi); idx >= 0 {
+		return idx
+	}
+	if start > lo {
+		return pm.scanFreeForwardLocked(lo, lo, start-1)
 	}
+	return -1
+}
+
+// collectFreeIndicesLocked 按位图顺序收集所有空闲位的下标，调用方需持有pm.mu。
+// 借助bits.TrailingZeros64逐个跳到word内下一个空闲位，整体开销为O(len(bitmap)+freeCount)。
+func (pm *PortManager) collectFreeIndicesLocked() []int {
+	indices := make([]int, 0, pm.freeCount)
+	for wi, word := range pm.bitmap {
+		free := ^word
+		for free != 0 {
+			bit := bits.TrailingZeros64(free)
+			indices = append(indices, wi*64+bit)
+			free &= free - 1 // 清除最低位的1
+		}
+	}
+	return indices
+}
+
+// isReservedByPoolLocked 判断位图第idx位对应的端口是否落在某个已定义pool的区间内，
+// 调用方需持有pm.mu（读锁或写锁均可）。全局分配器（GetRandomPort/GetRandomPorts/PeekRandomPort/
+// MarkPortUsed的非pool路径）靠它跳过被pool保留的端口，使pool真正成为独占区间，
+// 而不是一个仍可被全局分配悄悄侵入的子区间。
+func (pm *PortManager) isReservedByPoolLocked(idx int) bool {
+	port := pm.minPort + idx
+	for _, p := range pm.pools {
+		if port >= p.MinPort && port <= p.MaxPort {
+			return true
+		}
+	}
+	return false
+}
+
+// randIntn 返回[0,n)内的随机数，封装对pm.rng的加锁访问（*rand.Rand并非并发安全）
+func (pm *PortManager) randIntn(n int) int {
+	pm.mu.Lock()
+	v := pm.rng.Intn(n)
+	pm.mu.Unlock()
+	return v
 }
 
 // IsPortAvailable 检查端口是否可用
@@ -32,11 +219,12 @@ func (pm *PortManager) IsPortAvailable(port int) bool {
 		return false
 	}
 
-	// 检查是否已被标记为使用
+	// 检查位图中是否已被标记为使用
+	idx := port - pm.minPort
 	pm.mu.RLock()
-	used := pm.usedPorts[port]
+	free := pm.isFreeLocked(idx)
 	pm.mu.RUnlock()
-	if used {
+	if !free {
 		return false
 	}
 
@@ -68,48 +256,99 @@ func (pm *PortManager) checkPortBySocket(port int) bool {
 	return true
 }
 
-// GetRandomPort 获取一个随机可用端口
+// GetRandomPort 获取一个随机可用端口。从一个随机起始位开始在位图上做位扫描定位空闲端口，
+// 跳过逻辑空闲但被系统占用的端口，以及落在任意pool保留区间内的端口（pool是独占区间，
+// 全局分配不应侵入），最坏情况下遍历一次完整区间。
 func (pm *PortManager) GetRandomPort() (int, error) {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
+	size := pm.maxPort - pm.minPort + 1
 
-	// 最多尝试100次
-	for i := 0; i < 100; i++ {
-		port := rand.Intn(pm.maxPort-pm.minPort+1) + pm.minPort
-		if pm.IsPortAvailable(port) {
-			pm.markPortAsUsed(port)
-			return port, nil
+	pm.mu.Lock()
+	if pm.freeCount == 0 {
+		pm.mu.Unlock()
+		return 0, fmt.Errorf("无法找到可用端口")
+	}
+	start := pm.rng.Intn(size)
+	pm.mu.Unlock()
+
+	for tried := 0; tried < size; tried++ {
+		pm.mu.Lock()
+		idx := pm.nextFreeBitLocked(start)
+		if idx < 0 {
+			pm.mu.Unlock()
+			return 0, fmt.Errorf("无法找到可用端口")
+		}
+		reserved := pm.isReservedByPoolLocked(idx)
+		pm.mu.Unlock()
+		if reserved {
+			// 该端口已被某个pool保留为独占区间，全局分配器不应侵入
+			start = idx + 1
+			continue
+		}
+
+		candidate := pm.minPort + idx
+		if !pm.checkPortBySocket(candidate) {
+			start = idx + 1
+			continue
+		}
+
+		pm.mu.Lock()
+		if !pm.isFreeLocked(idx) {
+			// 扫描与socket检查之间该位已被其他goroutine抢占
+			pm.mu.Unlock()
+			start = idx + 1
+			continue
 		}
+		pm.setBitLocked(idx)
+		pm.freeCount--
+		pm.mu.Unlock()
+
+		return candidate, nil
 	}
 
 	return 0, fmt.Errorf("无法找到可用端口")
 }
 
-// GetRandomPorts 获取N个随机可用端口
+// GetRandomPorts 获取N个随机可用端口，通过对空闲端口集合做部分Fisher-Yates洗牌（蓄水池抽样）实现，
+// 保证结果不重复，且区间接近占满时也不会浪费迭代次数。被任意pool保留的端口会被排除在候选集合之外，
+// 使pool成为真正的独占区间。
 func (pm *PortManager) GetRandomPorts(count int) ([]int, error) {
 	if count <= 0 {
 		return nil, fmt.Errorf("端口数量必须大于0")
 	}
 
+	pm.mu.Lock()
+	all := pm.collectFreeIndicesLocked()
+	free := make([]int, 0, len(all))
+	for _, idx := range all {
+		if !pm.isReservedByPoolLocked(idx) {
+			free = append(free, idx)
+		}
+	}
+	pm.mu.Unlock()
+
+	n := len(free)
 	ports := make([]int, 0, count)
-	rand.Seed(time.Now().UnixNano())
-
-	// 最多尝试count*100次
-	for i := 0; i < count*100 && len(ports) < count; i++ {
-		port := rand.Intn(pm.maxPort-pm.minPort+1) + pm.minPort
-		if pm.IsPortAvailable(port) {
-			// 检查是否已经在结果中
-			alreadyExists := false
-			for _, p := range ports {
-				if p == port {
-					alreadyExists = true
-					break
-				}
-			}
-			if !alreadyExists {
-				pm.markPortAsUsed(port)
-				ports = append(ports, port)
-			}
+
+	for i := 0; i < n && len(ports) < count; i++ {
+		j := i + pm.randIntn(n-i)
+		free[i], free[j] = free[j], free[i]
+
+		idx := free[i]
+		candidate := pm.minPort + idx
+		if !pm.checkPortBySocket(candidate) {
+			continue
+		}
+
+		pm.mu.Lock()
+		if !pm.isFreeLocked(idx) {
+			pm.mu.Unlock()
+			continue
 		}
+		pm.setBitLocked(idx)
+		pm.freeCount--
+		pm.mu.Unlock()
+
+		ports = append(ports, candidate)
 	}
 
 	if len(ports) < count {
@@ -119,18 +358,298 @@ func (pm *PortManager) GetRandomPorts(count int) ([]int, error) {
 	return ports, nil
 }
 
-// markPortAsUsed 标记端口为已使用
-func (pm *PortManager) markPortAsUsed(port int) {
+// ReleasePort 释放端口
+func (pm *PortManager) ReleasePort(port int) {
+	size := pm.maxPort - pm.minPort + 1
+	idx := port - pm.minPort
+
 	pm.mu.Lock()
-	pm.usedPorts[port] = true
+	if idx >= 0 && idx < size && !pm.isFreeLocked(idx) {
+		pm.clearBitLocked(idx)
+		pm.freeCount++
+	}
+	pm.releasePortOwnershipLocked(port)
 	pm.mu.Unlock()
 }
 
-// ReleasePort 释放端口
-func (pm *PortManager) ReleasePort(port int) {
+// releasePortOwnershipLocked 归还端口占用的pool配额，调用方需持有pm.mu写锁
+func (pm *PortManager) releasePortOwnershipLocked(port int) {
+	owner, exists := pm.portOwner[port]
+	if !exists {
+		return
+	}
+	delete(pm.portOwner, port)
+	if usage, ok := pm.poolUsage[owner.pool]; ok {
+		usage[owner.tenant]--
+		if usage[owner.tenant] <= 0 {
+			delete(usage, owner.tenant)
+		}
+	}
+}
+
+// CreatePool 创建一个命名端口池，其区间必须落在PortManager的[minPort, maxPort]之内
+func (pm *PortManager) CreatePool(name string, minPort, maxPort, quota int) error {
+	if name == "" {
+		return fmt.Errorf("pool名称不能为空")
+	}
+	if minPort <= 0 || maxPort <= 0 || minPort > maxPort {
+		return fmt.Errorf("无效的端口范围: %d-%d", minPort, maxPort)
+	}
+	if quota < 0 {
+		return fmt.Errorf("配额不能为负数")
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if minPort < pm.minPort || maxPort > pm.maxPort {
+		return fmt.Errorf("pool区间 %d-%d 超出管理器范围 %d-%d", minPort, maxPort, pm.minPort, pm.maxPort)
+	}
+	if _, exists := pm.pools[name]; exists {
+		return fmt.Errorf("pool '%s' 已存在", name)
+	}
+
+	pm.pools[name] = &Pool{Name: name, MinPort: minPort, MaxPort: maxPort, Quota: quota}
+	pm.poolUsage[name] = make(map[string]int)
+
+	return nil
+}
+
+// DeletePool 删除一个命名端口池的定义，不影响已分配端口的占用状态
+func (pm *PortManager) DeletePool(name string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, exists := pm.pools[name]; !exists {
+		return fmt.Errorf("pool '%s' 不存在", name)
+	}
+	delete(pm.pools, name)
+	delete(pm.poolUsage, name)
+
+	return nil
+}
+
+// ListPools 列出当前所有命名端口池
+func (pm *PortManager) ListPools() []Pool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	result := make([]Pool, 0, len(pm.pools))
+	for _, p := range pm.pools {
+		result = append(result, *p)
+	}
+	return result
+}
+
+// GetRandomPortFromPool 从指定pool中获取一个随机可用端口，tenant为空字符串时不做配额限制。
+// 通过nextFreeBitInRangeLocked将扫描限定在该pool的索引子区间内，即便pool接近占满也能可靠地
+// 定位到仍然空闲的端口，而不是依赖随机试探。
+func (pm *PortManager) GetRandomPortFromPool(poolName, tenant string) (int, error) {
+	pm.mu.RLock()
+	pool, exists := pm.pools[poolName]
+	pm.mu.RUnlock()
+	if !exists {
+		return 0, fmt.Errorf("pool '%s' 不存在", poolName)
+	}
+
+	if tenant != "" && pool.Quota > 0 {
+		pm.mu.RLock()
+		used := pm.poolUsage[poolName][tenant]
+		pm.mu.RUnlock()
+		if used >= pool.Quota {
+			return 0, ErrQuotaExceeded
+		}
+	}
+
+	loIdx := pool.MinPort - pm.minPort
+	hiIdx := pool.MaxPort - pm.minPort
+	rangeSize := hiIdx - loIdx + 1
+
+	start := loIdx + pm.randIntn(rangeSize)
+
+	for tried := 0; tried < rangeSize; tried++ {
+		pm.mu.Lock()
+		idx := pm.nextFreeBitInRangeLocked(start, loIdx, hiIdx)
+		if idx < 0 {
+			pm.mu.Unlock()
+			return 0, fmt.Errorf("pool '%s' 中无法找到可用端口", poolName)
+		}
+		pm.mu.Unlock()
+
+		candidate := pm.minPort + idx
+		if !pm.checkPortBySocket(candidate) {
+			start = idx + 1
+			continue
+		}
+
+		pm.mu.Lock()
+		if !pm.isFreeLocked(idx) {
+			// 扫描与socket检查之间该位已被其他goroutine抢占
+			pm.mu.Unlock()
+			start = idx + 1
+			continue
+		}
+		if tenant != "" && pool.Quota > 0 && pm.poolUsage[poolName][tenant] >= pool.Quota {
+			pm.mu.Unlock()
+			return 0, ErrQuotaExceeded
+		}
+		pm.setBitLocked(idx)
+		pm.freeCount--
+		if tenant != "" {
+			pm.portOwner[candidate] = tenantPortKey{pool: poolName, tenant: tenant}
+			pm.poolUsage[poolName][tenant]++
+		}
+		pm.mu.Unlock()
+
+		return candidate, nil
+	}
+
+	return 0, fmt.Errorf("pool '%s' 中无法找到可用端口", poolName)
+}
+
+// PeekRandomPort 在不修改状态的前提下选出一个当前空闲、且未被任意pool保留的随机端口，
+// 用于集群模式下由leader在提交Raft日志前预先确定要分配的端口；真正的占用需随后通过
+// MarkPortUsed在所有副本上回放。
+func (pm *PortManager) PeekRandomPort() (int, error) {
+	size := pm.maxPort - pm.minPort + 1
+
 	pm.mu.Lock()
-	delete(pm.usedPorts, port)
+	if pm.freeCount == 0 {
+		pm.mu.Unlock()
+		return 0, fmt.Errorf("无法找到可用端口")
+	}
+	start := pm.rng.Intn(size)
 	pm.mu.Unlock()
+
+	for tried := 0; tried < size; tried++ {
+		pm.mu.Lock()
+		idx := pm.nextFreeBitLocked(start)
+		reserved := idx >= 0 && pm.isReservedByPoolLocked(idx)
+		pm.mu.Unlock()
+		if idx < 0 {
+			return 0, fmt.Errorf("无法找到可用端口")
+		}
+		if reserved {
+			// 该端口已被某个pool保留为独占区间，全局分配器不应侵入
+			start = idx + 1
+			continue
+		}
+
+		candidate := pm.minPort + idx
+		if !pm.checkPortBySocket(candidate) {
+			start = idx + 1
+			continue
+		}
+
+		pm.mu.RLock()
+		free := pm.isFreeLocked(idx)
+		pm.mu.RUnlock()
+		if !free {
+			start = idx + 1
+			continue
+		}
+
+		return candidate, nil
+	}
+
+	return 0, fmt.Errorf("无法找到可用端口")
+}
+
+// PeekRandomPortFromPool 是GetRandomPortFromPool的只读版本：校验配额并选出候选端口，
+// 但不占用位图、不计入poolUsage，供集群leader预先确定端口后再提交Raft日志。
+func (pm *PortManager) PeekRandomPortFromPool(poolName, tenant string) (int, error) {
+	pm.mu.RLock()
+	pool, exists := pm.pools[poolName]
+	pm.mu.RUnlock()
+	if !exists {
+		return 0, fmt.Errorf("pool '%s' 不存在", poolName)
+	}
+
+	if tenant != "" && pool.Quota > 0 {
+		pm.mu.RLock()
+		used := pm.poolUsage[poolName][tenant]
+		pm.mu.RUnlock()
+		if used >= pool.Quota {
+			return 0, ErrQuotaExceeded
+		}
+	}
+
+	loIdx := pool.MinPort - pm.minPort
+	hiIdx := pool.MaxPort - pm.minPort
+	rangeSize := hiIdx - loIdx + 1
+
+	start := loIdx + pm.randIntn(rangeSize)
+
+	for tried := 0; tried < rangeSize; tried++ {
+		pm.mu.Lock()
+		idx := pm.nextFreeBitInRangeLocked(start, loIdx, hiIdx)
+		pm.mu.Unlock()
+		if idx < 0 {
+			return 0, fmt.Errorf("pool '%s' 中无法找到可用端口", poolName)
+		}
+
+		candidate := pm.minPort + idx
+		if !pm.checkPortBySocket(candidate) {
+			start = idx + 1
+			continue
+		}
+
+		pm.mu.RLock()
+		free := pm.isFreeLocked(idx)
+		pm.mu.RUnlock()
+		if !free {
+			start = idx + 1
+			continue
+		}
+
+		return candidate, nil
+	}
+
+	return 0, fmt.Errorf("pool '%s' 中无法找到可用端口", poolName)
+}
+
+// MarkPortUsed 将一个已由集群leader确定好的端口标记为已占用，供FSM.Apply在所有副本上
+// 确定性地回放同一条Raft日志调用。poolName非空时同时校验并计入该pool的租户配额；
+// poolName为空时则校验该端口未落在任意pool的独占区间内，避免全局分配/租约侵入pool保留区间。
+// 若端口已被占用、越界或配额已满则返回错误，由调用方决定如何处理（各副本会得到一致的结果）。
+func (pm *PortManager) MarkPortUsed(port int, poolName, tenant string) error {
+	size := pm.maxPort - pm.minPort + 1
+	idx := port - pm.minPort
+	if idx < 0 || idx >= size {
+		return fmt.Errorf("端口 %d 超出管理范围 %d-%d", port, pm.minPort, pm.maxPort)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.isFreeLocked(idx) {
+		return fmt.Errorf("端口 %d 已被占用", port)
+	}
+
+	if poolName != "" {
+		pool, exists := pm.pools[poolName]
+		if !exists {
+			return fmt.Errorf("pool '%s' 不存在", poolName)
+		}
+		if idx < pool.MinPort-pm.minPort || idx > pool.MaxPort-pm.minPort {
+			return fmt.Errorf("端口 %d 不在pool '%s' 的区间内", port, poolName)
+		}
+		if tenant != "" && pool.Quota > 0 && pm.poolUsage[poolName][tenant] >= pool.Quota {
+			return ErrQuotaExceeded
+		}
+	} else if pm.isReservedByPoolLocked(idx) {
+		// 非pool分配不能侵入任何pool的独占区间
+		return fmt.Errorf("端口 %d 已被某个pool保留，无法作为全局端口分配", port)
+	}
+
+	pm.setBitLocked(idx)
+	pm.freeCount--
+	if poolName != "" && tenant != "" {
+		pm.portOwner[port] = tenantPortKey{pool: poolName, tenant: tenant}
+		pm.poolUsage[poolName][tenant]++
+	}
+
+	return nil
 }
 
 // SetPortRange 设置端口范围
@@ -139,9 +658,20 @@ func (pm *PortManager) SetPortRange(minPort, maxPort int) error {
 		return fmt.Errorf("无效的端口范围: %d-%d", minPort, maxPort)
 	}
 
+	size := maxPort - minPort + 1
+	words := (size + 63) / 64
+	bitmap := make([]uint64, words)
+	if validInLast := size - (words-1)*64; validInLast < 64 && words > 0 {
+		for i := validInLast; i < 64; i++ {
+			bitmap[words-1] |= 1 << uint(i)
+		}
+	}
+
 	pm.mu.Lock()
 	pm.minPort = minPort
 	pm.maxPort = maxPort
+	pm.bitmap = bitmap
+	pm.freeCount = size
 	pm.mu.Unlock()
 
 	return nil
@@ -0,0 +1,62 @@
+package port
+
+import (
+	"testing"
+)
+
+// newSaturatedManager 构造一个端口管理器，并预先占用约saturation比例的端口，用于在高占用率下衡量分配性能
+func newSaturatedManager(b *testing.B, minPort, maxPort int, saturation float64) *PortManager {
+	pm := NewPortManager(minPort, maxPort)
+	size := maxPort - minPort + 1
+	toFill := int(float64(size) * saturation)
+
+	pm.mu.Lock()
+	for i := 0; i < toFill; i++ {
+		pm.setBitLocked(i)
+	}
+	pm.freeCount = size - toFill
+	pm.mu.Unlock()
+
+	return pm
+}
+
+// BenchmarkGetRandomPort_Sparse 衡量端口区间基本空闲时单次分配的耗时。每次分配后立即释放该端口，
+// 避免随着b.N增长耗尽区间内的可用端口。
+func BenchmarkGetRandomPort_Sparse(b *testing.B) {
+	pm := NewPortManager(20000, 60000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, err := pm.GetRandomPort()
+		if err != nil {
+			b.Fatalf("分配端口失败: %v", err)
+		}
+		pm.ReleasePort(p)
+	}
+}
+
+// BenchmarkGetRandomPort_Saturated90 衡量端口区间90%已占用时单次分配的耗时。每次分配后立即释放该端口，
+// 使占用率在整个基准测试过程中维持在约90%，而不是随着b.N增长被耗尽。
+func BenchmarkGetRandomPort_Saturated90(b *testing.B) {
+	pm := newSaturatedManager(b, 20000, 60000, 0.9)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, err := pm.GetRandomPort()
+		if err != nil {
+			b.Fatalf("分配端口失败: %v", err)
+		}
+		pm.ReleasePort(p)
+	}
+}
+
+// BenchmarkGetRandomPorts_Batch100 衡量一次性批量分配100个端口的耗时
+func BenchmarkGetRandomPorts_Batch100(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		pm := NewPortManager(20000, 60000)
+		b.StartTimer()
+
+		if _, err := pm.GetRandomPorts(100); err != nil {
+			b.Fatalf("批量分配端口失败: %v", err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -10,19 +11,57 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/JJApplication/Themis/internal/cluster"
 	"github.com/JJApplication/Themis/internal/config"
+	"github.com/JJApplication/Themis/internal/health"
 	"github.com/JJApplication/Themis/internal/port"
 	"github.com/JJApplication/Themis/internal/storage"
 	pb "github.com/JJApplication/Themis/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
+// tenantMetadataKey 客户端通过gRPC metadata传递的租户标识字段名。
+// 注意：当前grpc.NewServer()未配置TransportCredentials，连接是明文的，不存在client证书，
+// 因此租户标识只能来自这个metadata字段，没有证书CN可作为兜底来源。
+const tenantMetadataKey = "tenant"
+
+// tenantFromContext 从请求的gRPC metadata中提取租户标识，用于pool配额统计；取不到时返回空字符串表示不限制配额
+func tenantFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(tenantMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
 // PortServer GRPC端口服务实现
 type PortServer struct {
 	pb.UnimplementedPortServiceServer
-	portManager *port.PortManager       // 端口管理器
-	storage     *storage.AppPortStorage // 存储管理器
-	config      *config.Config          // 配置
+	portManager   *port.PortManager // 端口管理器
+	store         storage.Store     // 存储后端，由config.Storage.Driver决定具体实现
+	config        *config.Config    // 配置
+	healthChecker *health.Checker   // 端口存活探测器
+	cluster       *cluster.Cluster  // 集群（未启用集群模式时为nil）
+}
+
+// newStore 根据存储配置创建对应的存储后端
+func newStore(cfg config.StorageConfig) (storage.Store, error) {
+	switch cfg.Driver {
+	case "", "json":
+		syncInterval := time.Duration(cfg.SyncInterval) * time.Second
+		return storage.NewJSONFileStore(cfg.DataFile, syncInterval), nil
+	case "bolt":
+		return storage.NewBoltStore(cfg.BoltPath)
+	case "etcd":
+		return storage.NewEtcdStore(cfg.EtcdEndpoints, 5*time.Second)
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s", cfg.Driver)
+	}
 }
 
 // NewPortServer 创建新的端口服务
@@ -30,26 +69,77 @@ func NewPortServer(cfg *config.Config) *PortServer {
 	// 创建端口管理器
 	portManager := port.NewPortManager(cfg.Port.MinPort, cfg.Port.MaxPort)
 
-	// 创建存储管理器
-	syncInterval := time.Duration(cfg.Storage.SyncInterval) * time.Second
-	storageManager := storage.NewAppPortStorage(cfg.Storage.DataFile, syncInterval)
+	// 创建存储后端
+	store, err := newStore(cfg.Storage)
+	if err != nil {
+		log.Fatalf("创建存储后端失败: %v", err)
+	}
+
+	// 创建健康探测器
+	healthChecker := health.NewChecker(health.Config{
+		ProbeInterval:    time.Duration(cfg.Health.ProbeInterval) * time.Second,
+		Timeout:          time.Duration(cfg.Health.Timeout) * time.Millisecond,
+		FailureThreshold: cfg.Health.FailureThreshold,
+		Protocols:        cfg.Health.Protocols,
+		AutoRelease:      cfg.Health.AutoRelease,
+	}, store, portManager)
 
 	return &PortServer{
-		portManager: portManager,
-		storage:     storageManager,
-		config:      cfg,
+		portManager:   portManager,
+		store:         store,
+		config:        cfg,
+		healthChecker: healthChecker,
 	}
 }
 
 // Start 启动服务
 func (s *PortServer) Start() error {
-	// 加载存储数据
-	if err := s.storage.LoadFromFile(); err != nil {
-		return fmt.Errorf("加载存储数据失败: %v", err)
+	// 加载存储数据（仅文件型存储后端需要）
+	if fileStore, ok := s.store.(storage.FileBackedStore); ok {
+		if err := fileStore.LoadFromFile(); err != nil {
+			return fmt.Errorf("加载存储数据失败: %v", err)
+		}
 	}
 
-	// 启动自动同步
-	s.storage.StartAutoSync()
+	// 重建已持久化的端口池定义（仅支持pool持久化的存储后端可用）
+	if poolStore, ok := s.store.(storage.PoolStore); ok {
+		for _, p := range poolStore.ListPools() {
+			if err := s.portManager.CreatePool(p.Name, p.MinPort, p.MaxPort, p.Quota); err != nil {
+				log.Printf("恢复端口池 '%s' 失败: %v", p.Name, err)
+			}
+		}
+	}
+
+	// 启用集群模式时，启动Raft节点，由Raft复制所有端口状态变更
+	if s.config.Cluster.Enabled {
+		c, err := cluster.New(cluster.Config{
+			NodeID:      s.config.Cluster.NodeID,
+			BindAddr:    s.config.Cluster.BindAddr,
+			Peers:       s.config.Cluster.Peers,
+			SnapshotDir: s.config.Cluster.SnapshotDir,
+		}, s.store, s.portManager)
+		if err != nil {
+			return fmt.Errorf("启动集群节点失败: %v", err)
+		}
+		s.cluster = c
+		s.healthChecker.SetCluster(c)
+	}
+
+	// 启动自动同步（仅文件型存储后端需要）
+	if fileStore, ok := s.store.(storage.FileBackedStore); ok {
+		fileStore.StartAutoSync()
+	}
+
+	// 启动租约回收协程，回收崩溃客户端未调用DeleteAppPort而遗留的端口（仅支持租约的存储后端可用）
+	if leaseStore, ok := s.store.(storage.LeaseStore); ok {
+		syncInterval := time.Duration(s.config.Storage.SyncInterval) * time.Second
+		leaseStore.StartLeaseReaper(syncInterval, func(appName string, port int) {
+			s.releaseExpiredLease(port)
+		})
+	}
+
+	// 启动端口存活探测器
+	s.healthChecker.Start()
 
 	// 创建gRPC服务器
 	grpcServer := grpc.NewServer()
@@ -79,12 +169,40 @@ func (s *PortServer) Start() error {
 
 	// 优雅关闭
 	grpcServer.GracefulStop()
-	s.storage.StopAutoSync()
+	s.healthChecker.Stop()
+	if leaseStore, ok := s.store.(storage.LeaseStore); ok {
+		leaseStore.StopLeaseReaper()
+	}
+	if fileStore, ok := s.store.(storage.FileBackedStore); ok {
+		fileStore.StopAutoSync()
+	}
+	if s.cluster != nil {
+		if err := s.cluster.Shutdown(); err != nil {
+			log.Printf("关闭集群节点失败: %v", err)
+		}
+	}
 
 	log.Println("服务已关闭")
 	return nil
 }
 
+// releaseExpiredLease 归还一个被租约回收协程判定为过期的端口。集群模式下只在leader节点上
+// 通过cluster.Apply(OpReleasePort)执行，确保所有副本以同一条Raft日志确定性地回放释放动作；
+// 租约本身不涉及AppPorts映射，因此用OpReleasePort而非OpDeleteAppPort。非leader节点什么都不做。
+func (s *PortServer) releaseExpiredLease(port int) {
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return
+		}
+		if _, err := s.cluster.Apply(cluster.Command{Op: cluster.OpReleasePort, Port: port}); err != nil {
+			log.Printf("集群回收过期租约端口 %d 失败: %v", port, err)
+		}
+		return
+	}
+
+	s.portManager.ReleasePort(port)
+}
+
 // createListener 创建监听器
 func (s *PortServer) createListener() (net.Listener, error) {
 	if s.config.Server.ListenType == "unix" {
@@ -102,8 +220,36 @@ func (s *PortServer) createListener() (net.Listener, error) {
 	}
 }
 
-// GetRandomPort 获取一个随机端口
+// GetRandomPort 获取一个随机端口，req.Pool不为空时从指定端口池中分配
 func (s *PortServer) GetRandomPort(ctx context.Context, req *pb.GetRandomPortRequest) (*pb.GetRandomPortResponse, error) {
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.GetRandomPortResponse{Error: s.notLeaderError()}, nil
+		}
+		tenant := tenantFromContext(ctx)
+		port, err := s.peekPort(req.Pool, tenant)
+		if err != nil {
+			return &pb.GetRandomPortResponse{Error: err.Error(), ErrorCode: errorCodeFor(err)}, nil
+		}
+		result, err := s.cluster.Apply(cluster.Command{Op: cluster.OpAcquirePort, Port: port, Pool: req.Pool, Tenant: tenant})
+		if err != nil {
+			return &pb.GetRandomPortResponse{Error: err.Error(), ErrorCode: errorCodeFor(err)}, nil
+		}
+		return &pb.GetRandomPortResponse{Port: int32(result.Port), Error: ""}, nil
+	}
+
+	if req.Pool != "" {
+		port, err := s.portManager.GetRandomPortFromPool(req.Pool, tenantFromContext(ctx))
+		if err != nil {
+			return &pb.GetRandomPortResponse{
+				Port:      0,
+				Error:     err.Error(),
+				ErrorCode: errorCodeFor(err),
+			}, nil
+		}
+		return &pb.GetRandomPortResponse{Port: int32(port), Error: ""}, nil
+	}
+
 	port, err := s.portManager.GetRandomPort()
 	if err != nil {
 		return &pb.GetRandomPortResponse{
@@ -118,7 +264,16 @@ func (s *PortServer) GetRandomPort(ctx context.Context, req *pb.GetRandomPortReq
 	}, nil
 }
 
-// GetRandomPorts 获取N个随机端口
+// peekPort 在不占用端口的前提下选出一个候选端口：pool非空时在该pool内按租户配额选取，
+// 否则在整个管理范围内选取。仅供集群leader在提交Raft命令前预先确定端口使用。
+func (s *PortServer) peekPort(pool, tenant string) (int, error) {
+	if pool != "" {
+		return s.portManager.PeekRandomPortFromPool(pool, tenant)
+	}
+	return s.portManager.PeekRandomPort()
+}
+
+// GetRandomPorts 获取N个随机端口，req.Pool不为空时从指定端口池中分配
 func (s *PortServer) GetRandomPorts(ctx context.Context, req *pb.GetRandomPortsRequest) (*pb.GetRandomPortsResponse, error) {
 	if req.Count <= 0 {
 		return &pb.GetRandomPortsResponse{
@@ -127,6 +282,43 @@ func (s *PortServer) GetRandomPorts(ctx context.Context, req *pb.GetRandomPortsR
 		}, nil
 	}
 
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.GetRandomPortsResponse{Error: s.notLeaderError()}, nil
+		}
+		tenant := tenantFromContext(ctx)
+		ports := make([]int32, 0, req.Count)
+		for i := int32(0); i < req.Count; i++ {
+			port, err := s.peekPort(req.Pool, tenant)
+			if err != nil {
+				return &pb.GetRandomPortsResponse{Ports: ports, Error: err.Error(), ErrorCode: errorCodeFor(err)}, nil
+			}
+			result, err := s.cluster.Apply(cluster.Command{Op: cluster.OpAcquirePort, Port: port, Pool: req.Pool, Tenant: tenant})
+			if err != nil {
+				return &pb.GetRandomPortsResponse{Ports: ports, Error: err.Error(), ErrorCode: errorCodeFor(err)}, nil
+			}
+			ports = append(ports, int32(result.Port))
+		}
+		return &pb.GetRandomPortsResponse{Ports: ports, Error: ""}, nil
+	}
+
+	if req.Pool != "" {
+		tenant := tenantFromContext(ctx)
+		ports := make([]int32, 0, req.Count)
+		for i := int32(0); i < req.Count; i++ {
+			port, err := s.portManager.GetRandomPortFromPool(req.Pool, tenant)
+			if err != nil {
+				return &pb.GetRandomPortsResponse{
+					Ports:     ports,
+					Error:     err.Error(),
+					ErrorCode: errorCodeFor(err),
+				}, nil
+			}
+			ports = append(ports, int32(port))
+		}
+		return &pb.GetRandomPortsResponse{Ports: ports, Error: ""}, nil
+	}
+
 	ports, err := s.portManager.GetRandomPorts(int(req.Count))
 	if err != nil {
 		return &pb.GetRandomPortsResponse{
@@ -149,7 +341,7 @@ func (s *PortServer) GetRandomPorts(ctx context.Context, req *pb.GetRandomPortsR
 
 // GetAppPort 获取某个APP的端口
 func (s *PortServer) GetAppPort(ctx context.Context, req *pb.GetAppPortRequest) (*pb.GetAppPortResponse, error) {
-	port, err := s.storage.GetAppPort(req.AppName)
+	port, err := s.store.GetAppPort(req.AppName)
 	if err != nil {
 		return &pb.GetAppPortResponse{
 			Port:  0,
@@ -165,7 +357,17 @@ func (s *PortServer) GetAppPort(ctx context.Context, req *pb.GetAppPortRequest)
 
 // SetAppPort 设置某个APP的端口
 func (s *PortServer) SetAppPort(ctx context.Context, req *pb.SetAppPortRequest) (*pb.SetAppPortResponse, error) {
-	err := s.storage.SetAppPort(req.AppName, int(req.Port))
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.SetAppPortResponse{Error: s.notLeaderError()}, nil
+		}
+		if _, err := s.cluster.Apply(cluster.Command{Op: cluster.OpSetAppPort, AppName: req.AppName, Port: int(req.Port)}); err != nil {
+			return &pb.SetAppPortResponse{Error: err.Error()}, nil
+		}
+		return &pb.SetAppPortResponse{Error: ""}, nil
+	}
+
+	err := s.store.SetAppPort(req.AppName, int(req.Port))
 	if err != nil {
 		return &pb.SetAppPortResponse{
 			Error: err.Error(),
@@ -177,11 +379,11 @@ func (s *PortServer) SetAppPort(ctx context.Context, req *pb.SetAppPortRequest)
 	}, nil
 }
 
-// QuickSetAppPort 快速设置APP端口（存在则返回，不存在则生成随机端口）
+// QuickSetAppPort 快速设置APP端口（存在则返回，不存在则生成随机端口）；req.Pool不为空时从指定端口池中分配
 func (s *PortServer) QuickSetAppPort(ctx context.Context, req *pb.QuickSetAppPortRequest) (*pb.QuickSetAppPortResponse, error) {
 	// 检查APP是否已存在
-	if s.storage.HasApp(req.AppName) {
-		port, err := s.storage.GetAppPort(req.AppName)
+	if s.store.HasApp(req.AppName) {
+		port, err := s.store.GetAppPort(req.AppName)
 		if err != nil {
 			return &pb.QuickSetAppPortResponse{
 				Port:  0,
@@ -194,17 +396,40 @@ func (s *PortServer) QuickSetAppPort(ctx context.Context, req *pb.QuickSetAppPor
 		}, nil
 	}
 
-	// 生成随机端口
-	port, err := s.portManager.GetRandomPort()
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.QuickSetAppPortResponse{Error: s.notLeaderError()}, nil
+		}
+		tenant := tenantFromContext(ctx)
+		port, err := s.peekPort(req.Pool, tenant)
+		if err != nil {
+			return &pb.QuickSetAppPortResponse{Error: err.Error(), ErrorCode: errorCodeFor(err)}, nil
+		}
+		result, err := s.cluster.Apply(cluster.Command{Op: cluster.OpAcquirePort, AppName: req.AppName, Port: port, Pool: req.Pool, Tenant: tenant})
+		if err != nil {
+			return &pb.QuickSetAppPortResponse{Error: err.Error(), ErrorCode: errorCodeFor(err)}, nil
+		}
+		return &pb.QuickSetAppPortResponse{Port: int32(result.Port), Error: ""}, nil
+	}
+
+	// 生成随机端口，pool不为空时在该pool内按租户配额分配
+	var port int
+	var err error
+	if req.Pool != "" {
+		port, err = s.portManager.GetRandomPortFromPool(req.Pool, tenantFromContext(ctx))
+	} else {
+		port, err = s.portManager.GetRandomPort()
+	}
 	if err != nil {
 		return &pb.QuickSetAppPortResponse{
-			Port:  0,
-			Error: err.Error(),
+			Port:      0,
+			Error:     err.Error(),
+			ErrorCode: errorCodeFor(err),
 		}, nil
 	}
 
 	// 设置APP端口
-	if err := s.storage.SetAppPort(req.AppName, port); err != nil {
+	if err := s.store.SetAppPort(req.AppName, port); err != nil {
 		return &pb.QuickSetAppPortResponse{
 			Port:  0,
 			Error: err.Error(),
@@ -225,13 +450,23 @@ func (s *PortServer) DeleteAppPort(ctx context.Context, req *pb.DeleteAppPortReq
 		}, nil
 	}
 
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.DeleteAppPortResponse{Error: s.notLeaderError()}, nil
+		}
+		if _, err := s.cluster.Apply(cluster.Command{Op: cluster.OpDeleteAppPort, AppName: req.AppName}); err != nil {
+			return &pb.DeleteAppPortResponse{Error: err.Error()}, nil
+		}
+		return &pb.DeleteAppPortResponse{Error: ""}, nil
+	}
+
 		// 获取端口以便释放
-	if port, err := s.storage.GetAppPort(req.AppName); err == nil {
+	if port, err := s.store.GetAppPort(req.AppName); err == nil {
 		s.portManager.ReleasePort(port)
 	}
 
 	// 删除APP端口信息
-	err := s.storage.DeleteAppPort(req.AppName)
+	err := s.store.DeleteAppPort(req.AppName)
 	if err != nil {
 		return &pb.DeleteAppPortResponse{
 			Error: err.Error(),
@@ -243,6 +478,15 @@ func (s *PortServer) DeleteAppPort(ctx context.Context, req *pb.DeleteAppPortReq
 	}, nil
 }
 
+// notLeaderError 生成"当前节点非leader"的错误文案，附带leader地址供客户端重定向
+func (s *PortServer) notLeaderError() string {
+	leader := s.cluster.Leader()
+	if leader == "" {
+		return "当前集群没有leader"
+	}
+	return fmt.Sprintf("当前节点不是leader，请重定向到: %s", leader)
+}
+
 // IsPortAvailable 检查端口是否可用
 func (s *PortServer) IsPortAvailable(ctx context.Context, req *pb.IsPortAvailableRequest) (*pb.IsPortAvailableResponse, error) {
 	if req.Port <= 0 || req.Port > 65535 {
@@ -260,3 +504,321 @@ func (s *PortServer) IsPortAvailable(ctx context.Context, req *pb.IsPortAvailabl
 		Error:     "",
 	}, nil
 }
+
+// AcquireLease 申请一个带租约的端口，客户端崩溃未主动释放时由租约回收协程自动归还
+func (s *PortServer) AcquireLease(ctx context.Context, req *pb.AcquireLeaseRequest) (*pb.AcquireLeaseResponse, error) {
+	if req.AppName == "" {
+		return &pb.AcquireLeaseResponse{Error: "APP名称不能为空"}, nil
+	}
+	if req.TtlSeconds <= 0 {
+		return &pb.AcquireLeaseResponse{Error: "租约有效期必须大于0"}, nil
+	}
+
+	leaseStore, ok := s.store.(storage.LeaseStore)
+	if !ok {
+		return &pb.AcquireLeaseResponse{Error: "当前存储后端不支持端口租约功能"}, nil
+	}
+
+	ttl := time.Duration(req.TtlSeconds) * time.Second
+
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.AcquireLeaseResponse{Error: s.notLeaderError()}, nil
+		}
+		port, err := s.portManager.PeekRandomPort()
+		if err != nil {
+			return &pb.AcquireLeaseResponse{Error: err.Error()}, nil
+		}
+		// LeaseID与绝对过期时间均由leader一次性决定，随命令一起复制，保证所有副本回放结果一致
+		leaseID := fmt.Sprintf("lease-%s-%d", req.AppName, time.Now().UnixNano())
+		expiresAt := time.Now().Add(ttl).Unix()
+		result, err := s.cluster.Apply(cluster.Command{
+			Op:        cluster.OpAcquireLease,
+			AppName:   req.AppName,
+			Port:      port,
+			LeaseID:   leaseID,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return &pb.AcquireLeaseResponse{Error: err.Error()}, nil
+		}
+		return &pb.AcquireLeaseResponse{
+			LeaseId:   result.LeaseID,
+			Port:      int32(result.Port),
+			ExpiresAt: result.ExpiresAt,
+			Error:     "",
+		}, nil
+	}
+
+	port, err := s.portManager.GetRandomPort()
+	if err != nil {
+		return &pb.AcquireLeaseResponse{Error: err.Error()}, nil
+	}
+
+	record, err := leaseStore.CreateLease(req.AppName, port, ttl)
+	if err != nil {
+		s.portManager.ReleasePort(port)
+		return &pb.AcquireLeaseResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.AcquireLeaseResponse{
+		LeaseId:   record.LeaseID,
+		Port:      int32(record.Port),
+		ExpiresAt: record.ExpiresAt,
+		Error:     "",
+	}, nil
+}
+
+// RenewLease 续约一个已存在的端口租约
+func (s *PortServer) RenewLease(ctx context.Context, req *pb.RenewLeaseRequest) (*pb.RenewLeaseResponse, error) {
+	if req.LeaseId == "" {
+		return &pb.RenewLeaseResponse{Error: "租约ID不能为空"}, nil
+	}
+	if req.TtlSeconds <= 0 {
+		return &pb.RenewLeaseResponse{Error: "租约有效期必须大于0"}, nil
+	}
+
+	leaseStore, ok := s.store.(storage.LeaseStore)
+	if !ok {
+		return &pb.RenewLeaseResponse{Error: "当前存储后端不支持端口租约功能"}, nil
+	}
+
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.RenewLeaseResponse{Error: s.notLeaderError()}, nil
+		}
+		// 绝对过期时间由leader一次性算好后随命令复制，所有副本回放同一个时间戳
+		expiresAt := time.Now().Add(time.Duration(req.TtlSeconds) * time.Second).Unix()
+		result, err := s.cluster.Apply(cluster.Command{Op: cluster.OpRenewLease, LeaseID: req.LeaseId, ExpiresAt: expiresAt})
+		if err != nil {
+			return &pb.RenewLeaseResponse{Error: err.Error()}, nil
+		}
+		return &pb.RenewLeaseResponse{ExpiresAt: result.ExpiresAt, Error: ""}, nil
+	}
+
+	expiresAt, err := leaseStore.RenewLease(req.LeaseId, time.Duration(req.TtlSeconds)*time.Second)
+	if err != nil {
+		return &pb.RenewLeaseResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.RenewLeaseResponse{
+		ExpiresAt: expiresAt,
+		Error:     "",
+	}, nil
+}
+
+// ReleaseLease 主动释放一个端口租约，归还端口供其他APP使用
+func (s *PortServer) ReleaseLease(ctx context.Context, req *pb.ReleaseLeaseRequest) (*pb.ReleaseLeaseResponse, error) {
+	if req.LeaseId == "" {
+		return &pb.ReleaseLeaseResponse{Error: "租约ID不能为空"}, nil
+	}
+
+	leaseStore, ok := s.store.(storage.LeaseStore)
+	if !ok {
+		return &pb.ReleaseLeaseResponse{Error: "当前存储后端不支持端口租约功能"}, nil
+	}
+
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.ReleaseLeaseResponse{Error: s.notLeaderError()}, nil
+		}
+		if _, err := s.cluster.Apply(cluster.Command{Op: cluster.OpReleaseLease, LeaseID: req.LeaseId}); err != nil {
+			return &pb.ReleaseLeaseResponse{Error: err.Error()}, nil
+		}
+		return &pb.ReleaseLeaseResponse{Error: ""}, nil
+	}
+
+	record, err := leaseStore.ReleaseLease(req.LeaseId)
+	if err != nil {
+		return &pb.ReleaseLeaseResponse{Error: err.Error()}, nil
+	}
+	s.portManager.ReleasePort(record.Port)
+
+	return &pb.ReleaseLeaseResponse{Error: ""}, nil
+}
+
+// GetAppHealth 获取某个APP当前的健康状态
+func (s *PortServer) GetAppHealth(ctx context.Context, req *pb.GetAppHealthRequest) (*pb.GetAppHealthResponse, error) {
+	if req.AppName == "" {
+		return &pb.GetAppHealthResponse{Error: "APP名称不能为空"}, nil
+	}
+
+	appHealth, err := s.healthChecker.GetAppHealth(req.AppName)
+	if err != nil {
+		return &pb.GetAppHealthResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.GetAppHealthResponse{
+		Health: &pb.AppHealthStatus{
+			AppName:             appHealth.AppName,
+			Port:                int32(appHealth.Port),
+			Status:              string(appHealth.Status),
+			ConsecutiveFailures: int32(appHealth.ConsecutiveFailures),
+			LastCheck:           appHealth.LastCheck.Unix(),
+		},
+	}, nil
+}
+
+// StreamHealthEvents 订阅健康状态变化事件流，直到客户端断开或服务停止
+func (s *PortServer) StreamHealthEvents(req *pb.StreamHealthEventsRequest, stream pb.PortService_StreamHealthEventsServer) error {
+	events, cancel := s.healthChecker.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&pb.HealthEvent{
+				AppName:   event.AppName,
+				Port:      int32(event.Port),
+				Status:    string(event.Status),
+				Timestamp: event.Timestamp.Unix(),
+			}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// JoinCluster 将一个节点加入集群，只能在leader上调用
+func (s *PortServer) JoinCluster(ctx context.Context, req *pb.JoinClusterRequest) (*pb.JoinClusterResponse, error) {
+	if s.cluster == nil {
+		return &pb.JoinClusterResponse{Error: "集群模式未启用"}, nil
+	}
+	if !s.cluster.IsLeader() {
+		return &pb.JoinClusterResponse{Error: s.notLeaderError()}, nil
+	}
+	if err := s.cluster.Join(req.NodeId, req.Addr); err != nil {
+		return &pb.JoinClusterResponse{Error: err.Error()}, nil
+	}
+	return &pb.JoinClusterResponse{Error: ""}, nil
+}
+
+// LeaveCluster 将一个节点移出集群，只能在leader上调用
+func (s *PortServer) LeaveCluster(ctx context.Context, req *pb.LeaveClusterRequest) (*pb.LeaveClusterResponse, error) {
+	if s.cluster == nil {
+		return &pb.LeaveClusterResponse{Error: "集群模式未启用"}, nil
+	}
+	if !s.cluster.IsLeader() {
+		return &pb.LeaveClusterResponse{Error: s.notLeaderError()}, nil
+	}
+	if err := s.cluster.Leave(req.NodeId); err != nil {
+		return &pb.LeaveClusterResponse{Error: err.Error()}, nil
+	}
+	return &pb.LeaveClusterResponse{Error: ""}, nil
+}
+
+// GetLeader 查询当前集群leader的地址
+func (s *PortServer) GetLeader(ctx context.Context, req *pb.GetLeaderRequest) (*pb.GetLeaderResponse, error) {
+	if s.cluster == nil {
+		return &pb.GetLeaderResponse{Error: "集群模式未启用"}, nil
+	}
+	return &pb.GetLeaderResponse{LeaderAddr: s.cluster.Leader()}, nil
+}
+
+// WatchAppPorts 订阅APP端口映射的变化事件，直到客户端断开
+func (s *PortServer) WatchAppPorts(req *pb.WatchAppPortsRequest, stream pb.PortService_WatchAppPortsServer) error {
+	events, cancel := s.store.Watch()
+	defer cancel()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&pb.AppPortEvent{
+				AppName: event.AppName,
+				Port:    int32(event.Port),
+				Deleted: event.Deleted,
+			}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// errorCodeFor 将portManager返回的错误映射为机器可读的错误码，目前仅区分配额超限
+func errorCodeFor(err error) string {
+	if errors.Is(err, port.ErrQuotaExceeded) {
+		return "QUOTA_EXCEEDED"
+	}
+	return ""
+}
+
+// CreatePool 创建一个命名端口池
+func (s *PortServer) CreatePool(ctx context.Context, req *pb.CreatePoolRequest) (*pb.CreatePoolResponse, error) {
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.CreatePoolResponse{Error: s.notLeaderError()}, nil
+		}
+		cmd := cluster.Command{Op: cluster.OpCreatePool, Pool: req.Name, MinPort: int(req.MinPort), MaxPort: int(req.MaxPort), Quota: int(req.Quota)}
+		if _, err := s.cluster.Apply(cmd); err != nil {
+			return &pb.CreatePoolResponse{Error: err.Error()}, nil
+		}
+		return &pb.CreatePoolResponse{Error: ""}, nil
+	}
+
+	if err := s.portManager.CreatePool(req.Name, int(req.MinPort), int(req.MaxPort), int(req.Quota)); err != nil {
+		return &pb.CreatePoolResponse{Error: err.Error()}, nil
+	}
+
+	if poolStore, ok := s.store.(storage.PoolStore); ok {
+		if err := poolStore.SetPool(storage.PoolRecord{
+			Name:    req.Name,
+			MinPort: int(req.MinPort),
+			MaxPort: int(req.MaxPort),
+			Quota:   int(req.Quota),
+		}); err != nil {
+			return &pb.CreatePoolResponse{Error: err.Error()}, nil
+		}
+	}
+
+	return &pb.CreatePoolResponse{Error: ""}, nil
+}
+
+// DeletePool 删除一个命名端口池的定义，不影响已分配端口的占用状态
+func (s *PortServer) DeletePool(ctx context.Context, req *pb.DeletePoolRequest) (*pb.DeletePoolResponse, error) {
+	if s.cluster != nil {
+		if !s.cluster.IsLeader() {
+			return &pb.DeletePoolResponse{Error: s.notLeaderError()}, nil
+		}
+		if _, err := s.cluster.Apply(cluster.Command{Op: cluster.OpDeletePool, Pool: req.Name}); err != nil {
+			return &pb.DeletePoolResponse{Error: err.Error()}, nil
+		}
+		return &pb.DeletePoolResponse{Error: ""}, nil
+	}
+
+	if err := s.portManager.DeletePool(req.Name); err != nil {
+		return &pb.DeletePoolResponse{Error: err.Error()}, nil
+	}
+
+	if poolStore, ok := s.store.(storage.PoolStore); ok {
+		if err := poolStore.DeletePool(req.Name); err != nil {
+			return &pb.DeletePoolResponse{Error: err.Error()}, nil
+		}
+	}
+
+	return &pb.DeletePoolResponse{Error: ""}, nil
+}
+
+// ListPools 列出当前所有命名端口池
+func (s *PortServer) ListPools(ctx context.Context, req *pb.ListPoolsRequest) (*pb.ListPoolsResponse, error) {
+	pools := s.portManager.ListPools()
+	result := make([]*pb.PoolInfo, 0, len(pools))
+	for _, p := range pools {
+		result = append(result, &pb.PoolInfo{
+			Name:    p.Name,
+			MinPort: int32(p.MinPort),
+			MaxPort: int32(p.MaxPort),
+			Quota:   int32(p.Quota),
+		})
+	}
+	return &pb.ListPoolsResponse{Pools: result, Error: ""}, nil
+}
@@ -0,0 +1,457 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// appPortsBucket BoltDB中存放APP端口映射的bucket名称
+var appPortsBucket = []byte("app_ports")
+
+// leasesBucket BoltDB中存放端口租约记录的bucket名称
+var leasesBucket = []byte("leases")
+
+// poolsBucket BoltDB中存放端口池定义的bucket名称
+var poolsBucket = []byte("pools")
+
+// BoltStore 基于BoltDB的单机事务型Store实现。相比JSONFileStore在每次写入都重写整个文件，
+// BoltStore只对变更的key做事务提交，适合APP数量较大的场景。同时实现了LeaseStore和PoolStore：
+// BoltDB本身没有原生TTL机制，租约到期沿用JSONFileStore的轮询回收方式，由StartLeaseReaper
+// 定期扫描leasesBucket中已过期的记录。
+type BoltStore struct {
+	db *bolt.DB
+
+	subMu       sync.Mutex
+	subscribers map[chan Event]struct{}
+
+	leaseStopChan chan struct{}
+	leaseWg       sync.WaitGroup
+}
+
+// NewBoltStore 打开（或创建）一个BoltDB文件作为端口存储后端
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		return nil, fmt.Errorf("打开BoltDB文件失败: %v", err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(appPortsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(leasesBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(poolsBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化BoltDB bucket失败: %v", err)
+	}
+
+	return &BoltStore{
+		db:            db,
+		subscribers:   make(map[chan Event]struct{}),
+		leaseStopChan: make(chan struct{}),
+	}, nil
+}
+
+// Close 关闭底层BoltDB文件
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+// SetAppPort 设置某个APP的端口
+func (b *BoltStore) SetAppPort(appName string, port int) error {
+	if appName == "" {
+		return fmt.Errorf("APP名称不能为空")
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("无效的端口号: %d", port)
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(appPortsBucket).Put([]byte(appName), encodePort(port))
+	})
+	if err != nil {
+		return fmt.Errorf("写入BoltDB失败: %v", err)
+	}
+
+	b.broadcast(Event{AppName: appName, Port: port})
+	return nil
+}
+
+// GetAppPort 获取某个APP的端口
+func (b *BoltStore) GetAppPort(appName string) (int, error) {
+	if appName == "" {
+		return 0, fmt.Errorf("APP名称不能为空")
+	}
+
+	var port int
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(appPortsBucket).Get([]byte(appName))
+		if value == nil {
+			return nil
+		}
+		found = true
+		port = decodePort(value)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("读取BoltDB失败: %v", err)
+	}
+	if !found {
+		return 0, fmt.Errorf("APP '%s' 不存在", appName)
+	}
+
+	return port, nil
+}
+
+// DeleteAppPort 删除某个APP的端口
+func (b *BoltStore) DeleteAppPort(appName string) error {
+	if appName == "" {
+		return fmt.Errorf("APP名称不能为空")
+	}
+
+	port, err := b.GetAppPort(appName)
+	if err != nil {
+		return err
+	}
+
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(appPortsBucket).Delete([]byte(appName))
+	}); err != nil {
+		return fmt.Errorf("删除BoltDB记录失败: %v", err)
+	}
+
+	b.broadcast(Event{AppName: appName, Port: port, Deleted: true})
+	return nil
+}
+
+// HasApp 检查APP是否存在
+func (b *BoltStore) HasApp(appName string) bool {
+	_, err := b.GetAppPort(appName)
+	return err == nil
+}
+
+// GetAllApps 列出当前所有APP端口映射
+func (b *BoltStore) GetAllApps() map[string]int {
+	result := make(map[string]int)
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(appPortsBucket).ForEach(func(k, v []byte) error {
+			result[string(k)] = decodePort(v)
+			return nil
+		})
+	})
+	return result
+}
+
+// Watch 订阅端口映射变化事件，返回的cancel函数用于取消订阅
+func (b *BoltStore) Watch() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	b.subMu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.subMu.Unlock()
+
+	cancel := func() {
+		b.subMu.Lock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+		b.subMu.Unlock()
+	}
+
+	return ch, cancel
+}
+
+// broadcast 将变化事件推送给所有Watch订阅者
+func (b *BoltStore) broadcast(event Event) {
+	b.subMu.Lock()
+	defer b.subMu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// CreateLease 创建一个端口租约并持久化
+func (b *BoltStore) CreateLease(appName string, port int, ttl time.Duration) (LeaseRecord, error) {
+	if appName == "" {
+		return LeaseRecord{}, fmt.Errorf("APP名称不能为空")
+	}
+	if ttl <= 0 {
+		return LeaseRecord{}, fmt.Errorf("租约有效期必须大于0")
+	}
+
+	record := LeaseRecord{
+		LeaseID:   fmt.Sprintf("lease-%s-%d", appName, time.Now().UnixNano()),
+		AppName:   appName,
+		Port:      port,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	if err := b.putLeaseRecord(record); err != nil {
+		return LeaseRecord{}, err
+	}
+	return record, nil
+}
+
+// CreateLeaseRecord 持久化一条字段已全部确定好的租约记录，不生成LeaseID也不基于本地时钟计算过期时间。
+// 供集群模式下FSM在所有节点上确定性地回放同一条Raft日志调用。
+func (b *BoltStore) CreateLeaseRecord(record LeaseRecord) error {
+	if record.LeaseID == "" {
+		return fmt.Errorf("租约ID不能为空")
+	}
+	if record.AppName == "" {
+		return fmt.Errorf("APP名称不能为空")
+	}
+	return b.putLeaseRecord(record)
+}
+
+// putLeaseRecord 将一条租约记录序列化后写入leasesBucket
+func (b *BoltStore) putLeaseRecord(record LeaseRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化租约记录失败: %v", err)
+	}
+
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(leasesBucket).Put([]byte(record.LeaseID), data)
+	}); err != nil {
+		return fmt.Errorf("写入BoltDB租约记录失败: %v", err)
+	}
+	return nil
+}
+
+// getLeaseRecord 读取一条租约记录
+func (b *BoltStore) getLeaseRecord(leaseID string) (LeaseRecord, error) {
+	var record LeaseRecord
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(leasesBucket).Get([]byte(leaseID))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &record)
+	})
+	if err != nil {
+		return LeaseRecord{}, fmt.Errorf("读取BoltDB租约记录失败: %v", err)
+	}
+	if !found {
+		return LeaseRecord{}, fmt.Errorf("租约 '%s' 不存在", leaseID)
+	}
+	return record, nil
+}
+
+// RenewLease 续约一个已存在的端口租约，返回新的过期时间戳
+func (b *BoltStore) RenewLease(leaseID string, ttl time.Duration) (int64, error) {
+	if leaseID == "" {
+		return 0, fmt.Errorf("租约ID不能为空")
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("租约有效期必须大于0")
+	}
+
+	record, err := b.getLeaseRecord(leaseID)
+	if err != nil {
+		return 0, err
+	}
+	record.ExpiresAt = time.Now().Add(ttl).Unix()
+	if err := b.putLeaseRecord(record); err != nil {
+		return 0, err
+	}
+	return record.ExpiresAt, nil
+}
+
+// RenewLeaseTo 将租约续期到一个已确定好的绝对过期时间戳，用途同CreateLeaseRecord：
+// 供集群模式下FSM在所有节点上确定性地回放同一条Raft日志调用。
+func (b *BoltStore) RenewLeaseTo(leaseID string, expiresAt int64) error {
+	if leaseID == "" {
+		return fmt.Errorf("租约ID不能为空")
+	}
+
+	record, err := b.getLeaseRecord(leaseID)
+	if err != nil {
+		return err
+	}
+	record.ExpiresAt = expiresAt
+	return b.putLeaseRecord(record)
+}
+
+// ReleaseLease 释放一个端口租约，返回被释放的租约记录以便调用方归还端口
+func (b *BoltStore) ReleaseLease(leaseID string) (LeaseRecord, error) {
+	if leaseID == "" {
+		return LeaseRecord{}, fmt.Errorf("租约ID不能为空")
+	}
+
+	record, err := b.getLeaseRecord(leaseID)
+	if err != nil {
+		return LeaseRecord{}, err
+	}
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(leasesBucket).Delete([]byte(leaseID))
+	}); err != nil {
+		return LeaseRecord{}, fmt.Errorf("删除BoltDB租约记录失败: %v", err)
+	}
+	return record, nil
+}
+
+// GetLease 获取某个租约的信息
+func (b *BoltStore) GetLease(leaseID string) (LeaseRecord, error) {
+	return b.getLeaseRecord(leaseID)
+}
+
+// ListLeases 获取当前所有租约
+func (b *BoltStore) ListLeases() []LeaseRecord {
+	var result []LeaseRecord
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(leasesBucket).ForEach(func(k, v []byte) error {
+			var record LeaseRecord
+			if err := json.Unmarshal(v, &record); err == nil {
+				result = append(result, record)
+			}
+			return nil
+		})
+	})
+	return result
+}
+
+// StartLeaseReaper 启动租约回收协程，定期扫描已过期的租约并通过onExpire归还端口。
+// BoltDB没有原生TTL机制，回收方式与JSONFileStore一致，依赖轮询而非事件推送。
+func (b *BoltStore) StartLeaseReaper(interval time.Duration, onExpire func(appName string, port int)) {
+	b.leaseWg.Add(1)
+	go func() {
+		defer b.leaseWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				b.reapExpiredLeases(onExpire)
+			case <-b.leaseStopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopLeaseReaper 停止租约回收协程
+func (b *BoltStore) StopLeaseReaper() {
+	close(b.leaseStopChan)
+	b.leaseWg.Wait()
+}
+
+// reapExpiredLeases 扫描并回收过期租约
+func (b *BoltStore) reapExpiredLeases(onExpire func(appName string, port int)) {
+	now := time.Now().Unix()
+	expired := make([]LeaseRecord, 0)
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(leasesBucket)
+		return bucket.ForEach(func(k, v []byte) error {
+			var record LeaseRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return nil
+			}
+			if record.ExpiresAt <= now {
+				expired = append(expired, record)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		fmt.Printf("扫描过期租约失败: %v\n", err)
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(leasesBucket)
+		for _, record := range expired {
+			if err := bucket.Delete([]byte(record.LeaseID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		fmt.Printf("租约回收后删除BoltDB记录失败: %v\n", err)
+		return
+	}
+
+	for _, record := range expired {
+		onExpire(record.AppName, record.Port)
+	}
+}
+
+// SetPool 创建或更新一个端口池定义并持久化
+func (b *BoltStore) SetPool(pool PoolRecord) error {
+	if pool.Name == "" {
+		return fmt.Errorf("pool名称不能为空")
+	}
+
+	data, err := json.Marshal(pool)
+	if err != nil {
+		return fmt.Errorf("序列化pool定义失败: %v", err)
+	}
+
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(poolsBucket).Put([]byte(pool.Name), data)
+	}); err != nil {
+		return fmt.Errorf("写入BoltDB pool定义失败: %v", err)
+	}
+	return nil
+}
+
+// DeletePool 删除一个端口池定义
+func (b *BoltStore) DeletePool(name string) error {
+	if name == "" {
+		return fmt.Errorf("pool名称不能为空")
+	}
+
+	if err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(poolsBucket).Delete([]byte(name))
+	}); err != nil {
+		return fmt.Errorf("删除BoltDB pool定义失败: %v", err)
+	}
+	return nil
+}
+
+// ListPools 列出当前所有持久化的端口池定义
+func (b *BoltStore) ListPools() []PoolRecord {
+	var result []PoolRecord
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(poolsBucket).ForEach(func(k, v []byte) error {
+			var pool PoolRecord
+			if err := json.Unmarshal(v, &pool); err == nil {
+				result = append(result, pool)
+			}
+			return nil
+		})
+	})
+	return result
+}
+
+// encodePort 将端口号编码为大端字节序，便于BoltDB的有序存储
+func encodePort(port int) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(port))
+	return buf
+}
+
+// decodePort 解码大端字节序的端口号
+func decodePort(data []byte) int {
+	return int(binary.BigEndian.Uint32(data))
+}
@@ -0,0 +1,496 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdKeyPrefix etcd中端口映射key的统一前缀，便于与其他业务共用同一个etcd集群
+const etcdKeyPrefix = "/themis/ports/"
+
+// etcdLeaseKeyPrefix etcd中端口租约记录key的统一前缀
+const etcdLeaseKeyPrefix = "/themis/leases/"
+
+// etcdPoolKeyPrefix etcd中端口池定义key的统一前缀
+const etcdPoolKeyPrefix = "/themis/pools/"
+
+// EtcdStore 基于etcd的Store实现，端口分配借助etcd的watch机制可被外部系统观察到，
+// 重启后无需额外同步，数据天然跟随etcd集群落盘。同时实现了LeaseStore和PoolStore：
+// 租约借助etcd原生lease机制实现TTL（到期由etcd自动删除key），端口池定义则是普通的
+// Put/Get/Delete，不依赖lease。
+type EtcdStore struct {
+	client *clientv3.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	subMu       sync.Mutex
+	subscribers map[chan Event]struct{}
+
+	leaseMu     sync.Mutex
+	leaseCancel context.CancelFunc
+}
+
+// etcdLeaseEntry 持久化在etcd中的租约记录，除对外暴露的LeaseRecord外还记录了
+// 承载该key的etcd内部lease ID，续约时需要据此撤销旧lease、绑定新lease。
+type etcdLeaseEntry struct {
+	Record    LeaseRecord `json:"record"`
+	EtcdLease int64       `json:"etcd_lease"`
+}
+
+// NewEtcdStore 连接到etcd集群，返回一个使用etcd作为后端的Store
+func NewEtcdStore(endpoints []string, dialTimeout time.Duration) (*EtcdStore, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: dialTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("连接etcd失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store := &EtcdStore{
+		client:      client,
+		ctx:         ctx,
+		cancel:      cancel,
+		subscribers: make(map[chan Event]struct{}),
+	}
+	store.watchRemoteChanges()
+
+	return store, nil
+}
+
+// Close 关闭etcd客户端连接
+func (e *EtcdStore) Close() error {
+	e.cancel()
+	return e.client.Close()
+}
+
+// SetAppPort 设置某个APP的端口
+func (e *EtcdStore) SetAppPort(appName string, port int) error {
+	if appName == "" {
+		return fmt.Errorf("APP名称不能为空")
+	}
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("无效的端口号: %d", port)
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := e.client.Put(ctx, etcdKeyPrefix+appName, strconv.Itoa(port)); err != nil {
+		return fmt.Errorf("写入etcd失败: %v", err)
+	}
+	return nil
+}
+
+// GetAppPort 获取某个APP的端口
+func (e *EtcdStore) GetAppPort(appName string) (int, error) {
+	if appName == "" {
+		return 0, fmt.Errorf("APP名称不能为空")
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, etcdKeyPrefix+appName)
+	if err != nil {
+		return 0, fmt.Errorf("读取etcd失败: %v", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return 0, fmt.Errorf("APP '%s' 不存在", appName)
+	}
+
+	port, err := strconv.Atoi(string(resp.Kvs[0].Value))
+	if err != nil {
+		return 0, fmt.Errorf("解析etcd中的端口号失败: %v", err)
+	}
+	return port, nil
+}
+
+// DeleteAppPort 删除某个APP的端口
+func (e *EtcdStore) DeleteAppPort(appName string) error {
+	if appName == "" {
+		return fmt.Errorf("APP名称不能为空")
+	}
+	if _, err := e.GetAppPort(appName); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := e.client.Delete(ctx, etcdKeyPrefix+appName); err != nil {
+		return fmt.Errorf("删除etcd记录失败: %v", err)
+	}
+	return nil
+}
+
+// HasApp 检查APP是否存在
+func (e *EtcdStore) HasApp(appName string) bool {
+	_, err := e.GetAppPort(appName)
+	return err == nil
+}
+
+// GetAllApps 列出当前所有APP端口映射
+func (e *EtcdStore) GetAllApps() map[string]int {
+	result := make(map[string]int)
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, etcdKeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return result
+	}
+
+	for _, kv := range resp.Kvs {
+		appName := strings.TrimPrefix(string(kv.Key), etcdKeyPrefix)
+		if port, err := strconv.Atoi(string(kv.Value)); err == nil {
+			result[appName] = port
+		}
+	}
+	return result
+}
+
+// Watch 订阅端口映射变化事件，返回的cancel函数用于取消订阅
+func (e *EtcdStore) Watch() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	e.subMu.Lock()
+	e.subscribers[ch] = struct{}{}
+	e.subMu.Unlock()
+
+	cancel := func() {
+		e.subMu.Lock()
+		if _, ok := e.subscribers[ch]; ok {
+			delete(e.subscribers, ch)
+			close(ch)
+		}
+		e.subMu.Unlock()
+	}
+
+	return ch, cancel
+}
+
+// watchRemoteChanges 监听etcd上的前缀变化并转发给本地Watch订阅者，
+// 使得端口分配可以被多个Themis实例或外部系统直接通过etcd观察到
+func (e *EtcdStore) watchRemoteChanges() {
+	watchChan := e.client.Watch(e.ctx, etcdKeyPrefix, clientv3.WithPrefix())
+
+	go func() {
+		for resp := range watchChan {
+			for _, ev := range resp.Events {
+				appName := strings.TrimPrefix(string(ev.Kv.Key), etcdKeyPrefix)
+				deleted := ev.Type == clientv3.EventTypeDelete
+
+				var port int
+				if !deleted {
+					port, _ = strconv.Atoi(string(ev.Kv.Value))
+				}
+
+				e.broadcast(Event{AppName: appName, Port: port, Deleted: deleted})
+			}
+		}
+	}()
+}
+
+// broadcast 将变化事件推送给所有Watch订阅者
+func (e *EtcdStore) broadcast(event Event) {
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+	for ch := range e.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// putLeaseEntry 申请一个TTL秒的etcd lease，并将record以该lease为生命周期写入etcdLeaseKeyPrefix+record.LeaseID
+func (e *EtcdStore) putLeaseEntry(ctx context.Context, record LeaseRecord, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+	leaseResp, err := e.client.Grant(ctx, int64(ttl.Seconds()))
+	if err != nil {
+		return fmt.Errorf("申请etcd lease失败: %v", err)
+	}
+
+	entry := etcdLeaseEntry{Record: record, EtcdLease: int64(leaseResp.ID)}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		e.client.Revoke(ctx, leaseResp.ID)
+		return fmt.Errorf("序列化租约记录失败: %v", err)
+	}
+
+	if _, err := e.client.Put(ctx, etcdLeaseKeyPrefix+record.LeaseID, string(data), clientv3.WithLease(leaseResp.ID)); err != nil {
+		e.client.Revoke(ctx, leaseResp.ID)
+		return fmt.Errorf("写入etcd租约记录失败: %v", err)
+	}
+	return nil
+}
+
+// getLeaseEntry 读取一条租约记录
+func (e *EtcdStore) getLeaseEntry(ctx context.Context, leaseID string) (etcdLeaseEntry, error) {
+	resp, err := e.client.Get(ctx, etcdLeaseKeyPrefix+leaseID)
+	if err != nil {
+		return etcdLeaseEntry{}, fmt.Errorf("读取etcd租约记录失败: %v", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return etcdLeaseEntry{}, fmt.Errorf("租约 '%s' 不存在", leaseID)
+	}
+
+	var entry etcdLeaseEntry
+	if err := json.Unmarshal(resp.Kvs[0].Value, &entry); err != nil {
+		return etcdLeaseEntry{}, fmt.Errorf("解析etcd租约记录失败: %v", err)
+	}
+	return entry, nil
+}
+
+// CreateLease 申请一个带TTL的端口租约，底层绑定一个同等TTL的etcd lease，
+// 租约到期时etcd会自动删除对应key，无需本地轮询回收。
+func (e *EtcdStore) CreateLease(appName string, port int, ttl time.Duration) (LeaseRecord, error) {
+	if appName == "" {
+		return LeaseRecord{}, fmt.Errorf("APP名称不能为空")
+	}
+	if ttl <= 0 {
+		return LeaseRecord{}, fmt.Errorf("租约有效期必须大于0")
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	record := LeaseRecord{
+		LeaseID:   fmt.Sprintf("lease-%s-%d", appName, time.Now().UnixNano()),
+		AppName:   appName,
+		Port:      port,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	if err := e.putLeaseEntry(ctx, record, ttl); err != nil {
+		return LeaseRecord{}, err
+	}
+	return record, nil
+}
+
+// CreateLeaseRecord 持久化一条字段已全部确定好的租约记录，供集群模式下FSM确定性回放Raft日志调用。
+// 注意：每个副本各自向etcd申请一个独立的lease来承载同一个LeaseID，这一步本身涉及与etcd的
+// 实时交互而非纯本地状态转换，因此在"EtcdStore同时作为Raft集群后端"这一组合场景下无法做到
+// 与JSONFileStore完全等价的确定性回放，建议该组合场景下改用bolt或json存储后端。
+func (e *EtcdStore) CreateLeaseRecord(record LeaseRecord) error {
+	if record.LeaseID == "" {
+		return fmt.Errorf("租约ID不能为空")
+	}
+	if record.AppName == "" {
+		return fmt.Errorf("APP名称不能为空")
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	ttl := time.Until(time.Unix(record.ExpiresAt, 0))
+	return e.putLeaseEntry(ctx, record, ttl)
+}
+
+// RenewLease 续约一个已存在的端口租约：申请一个新的TTL lease，将key重新绑定到新lease，
+// 并撤销旧lease。
+func (e *EtcdStore) RenewLease(leaseID string, ttl time.Duration) (int64, error) {
+	if leaseID == "" {
+		return 0, fmt.Errorf("租约ID不能为空")
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("租约有效期必须大于0")
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	entry, err := e.getLeaseEntry(ctx, leaseID)
+	if err != nil {
+		return 0, err
+	}
+
+	entry.Record.ExpiresAt = time.Now().Add(ttl).Unix()
+	if err := e.putLeaseEntry(ctx, entry.Record, ttl); err != nil {
+		return 0, err
+	}
+	e.client.Revoke(ctx, clientv3.LeaseID(entry.EtcdLease))
+
+	return entry.Record.ExpiresAt, nil
+}
+
+// RenewLeaseTo 将租约续期到一个已确定好的绝对过期时间戳，供集群模式下FSM确定性回放Raft日志调用。
+func (e *EtcdStore) RenewLeaseTo(leaseID string, expiresAt int64) error {
+	if leaseID == "" {
+		return fmt.Errorf("租约ID不能为空")
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	entry, err := e.getLeaseEntry(ctx, leaseID)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Until(time.Unix(expiresAt, 0))
+	entry.Record.ExpiresAt = expiresAt
+	if err := e.putLeaseEntry(ctx, entry.Record, ttl); err != nil {
+		return err
+	}
+	e.client.Revoke(ctx, clientv3.LeaseID(entry.EtcdLease))
+
+	return nil
+}
+
+// ReleaseLease 主动释放一个端口租约：删除key并立即撤销底层etcd lease，不等待TTL到期
+func (e *EtcdStore) ReleaseLease(leaseID string) (LeaseRecord, error) {
+	if leaseID == "" {
+		return LeaseRecord{}, fmt.Errorf("租约ID不能为空")
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	entry, err := e.getLeaseEntry(ctx, leaseID)
+	if err != nil {
+		return LeaseRecord{}, err
+	}
+
+	e.client.Revoke(ctx, clientv3.LeaseID(entry.EtcdLease))
+	return entry.Record, nil
+}
+
+// GetLease 获取一条租约记录
+func (e *EtcdStore) GetLease(leaseID string) (LeaseRecord, error) {
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	entry, err := e.getLeaseEntry(ctx, leaseID)
+	if err != nil {
+		return LeaseRecord{}, err
+	}
+	return entry.Record, nil
+}
+
+// ListLeases 列出当前所有未过期的租约记录
+func (e *EtcdStore) ListLeases() []LeaseRecord {
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, etcdLeaseKeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil
+	}
+
+	records := make([]LeaseRecord, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var entry etcdLeaseEntry
+		if err := json.Unmarshal(kv.Value, &entry); err == nil {
+			records = append(records, entry.Record)
+		}
+	}
+	return records
+}
+
+// StartLeaseReaper 订阅租约key的删除事件，无论是etcd lease到期自动删除还是ReleaseLease主动删除，
+// 都会回调onExpire归还端口；与JSONFileStore基于轮询的回收方式不同，这里完全依赖etcd的watch机制，
+// 不需要interval参数指定的轮询周期。
+func (e *EtcdStore) StartLeaseReaper(interval time.Duration, onExpire func(appName string, port int)) {
+	e.leaseMu.Lock()
+	if e.leaseCancel != nil {
+		e.leaseMu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(e.ctx)
+	e.leaseCancel = cancel
+	e.leaseMu.Unlock()
+
+	watchChan := e.client.Watch(ctx, etcdLeaseKeyPrefix, clientv3.WithPrefix(), clientv3.WithPrevKV())
+	go func() {
+		for resp := range watchChan {
+			for _, ev := range resp.Events {
+				if ev.Type != clientv3.EventTypeDelete || ev.PrevKv == nil {
+					continue
+				}
+				var entry etcdLeaseEntry
+				if err := json.Unmarshal(ev.PrevKv.Value, &entry); err != nil {
+					continue
+				}
+				onExpire(entry.Record.AppName, entry.Record.Port)
+			}
+		}
+	}()
+}
+
+// StopLeaseReaper 停止订阅租约key的删除事件
+func (e *EtcdStore) StopLeaseReaper() {
+	e.leaseMu.Lock()
+	defer e.leaseMu.Unlock()
+	if e.leaseCancel != nil {
+		e.leaseCancel()
+		e.leaseCancel = nil
+	}
+}
+
+// SetPool 持久化一个端口池定义
+func (e *EtcdStore) SetPool(pool PoolRecord) error {
+	if pool.Name == "" {
+		return fmt.Errorf("pool名称不能为空")
+	}
+
+	data, err := json.Marshal(pool)
+	if err != nil {
+		return fmt.Errorf("序列化pool定义失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := e.client.Put(ctx, etcdPoolKeyPrefix+pool.Name, string(data)); err != nil {
+		return fmt.Errorf("写入etcd pool定义失败: %v", err)
+	}
+	return nil
+}
+
+// DeletePool 删除一个端口池定义
+func (e *EtcdStore) DeletePool(name string) error {
+	if name == "" {
+		return fmt.Errorf("pool名称不能为空")
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := e.client.Delete(ctx, etcdPoolKeyPrefix+name); err != nil {
+		return fmt.Errorf("删除etcd pool定义失败: %v", err)
+	}
+	return nil
+}
+
+// ListPools 列出当前所有持久化的端口池定义
+func (e *EtcdStore) ListPools() []PoolRecord {
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, etcdPoolKeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil
+	}
+
+	pools := make([]PoolRecord, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var pool PoolRecord
+		if err := json.Unmarshal(kv.Value, &pool); err == nil {
+			pools = append(pools, pool)
+		}
+	}
+	return pools
+}
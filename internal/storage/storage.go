@@ -9,35 +9,64 @@ import (
 	"time"
 )
 
-// AppPortStorage APP端口存储管理器
-type AppPortStorage struct {
-	mu           sync.RWMutex
-	appPorts     map[string]int // APP名称到端口的映射
-	dataFile     string         // 数据文件路径
-	syncInterval time.Duration  // 同步间隔
-	stopChan     chan struct{}  // 停止信号
-	wg           sync.WaitGroup // 等待组
+// JSONFileStore APP端口存储管理器，基于单个JSON文件持久化，是Store接口的默认实现
+type JSONFileStore struct {
+	mu            sync.RWMutex
+	appPorts      map[string]int         // APP名称到端口的映射
+	leases        map[string]LeaseRecord // 租约ID到租约记录的映射
+	pools         map[string]PoolRecord  // pool名称到定义的映射
+	dataFile      string                 // 数据文件路径
+	syncInterval  time.Duration          // 同步间隔
+	stopChan      chan struct{}          // 停止信号
+	wg            sync.WaitGroup         // 等待组
+	leaseStopChan chan struct{}          // 租约回收停止信号
+	leaseWg       sync.WaitGroup         // 租约回收等待组
+
+	subMu       sync.Mutex
+	subscribers map[chan Event]struct{} // Watch订阅者
+}
+
+// LeaseRecord 端口租约记录（用于JSON序列化）
+type LeaseRecord struct {
+	LeaseID   string `json:"lease_id"`   // 租约ID
+	AppName   string `json:"app_name"`   // APP名称
+	Port      int    `json:"port"`       // 端口号
+	ExpiresAt int64  `json:"expires_at"` // 过期时间戳（秒）
+}
+
+// PoolRecord 端口池定义（用于JSON序列化）
+type PoolRecord struct {
+	Name    string `json:"name"`     // pool名称
+	MinPort int    `json:"min_port"` // 子区间最小端口号
+	MaxPort int    `json:"max_port"` // 子区间最大端口号
+	Quota   int    `json:"quota"`    // 单租户配额，0表示不限制
 }
 
 // PortData 端口数据结构（用于JSON序列化）
 type PortData struct {
-	AppPorts  map[string]int `json:"app_ports"`  // APP端口映射
-	Timestamp int64          `json:"timestamp"`  // 时间戳
-	Version   string         `json:"version"`    // 版本信息
+	AppPorts  map[string]int         `json:"app_ports"`        // APP端口映射
+	Leases    map[string]LeaseRecord `json:"leases,omitempty"` // 端口租约映射
+	Pools     map[string]PoolRecord  `json:"pools,omitempty"`  // 端口池定义
+	Timestamp int64                  `json:"timestamp"`        // 时间戳
+	Version   string                 `json:"version"`          // 版本信息
 }
 
-// NewAppPortStorage 创建新的APP端口存储管理器
-func NewAppPortStorage(dataFile string, syncInterval time.Duration) *AppPortStorage {
-	return &AppPortStorage{
-		appPorts:     make(map[string]int),
-		dataFile:     dataFile,
-		syncInterval: syncInterval,
-		stopChan:     make(chan struct{}),
+// NewJSONFileStore 创建新的APP端口存储管理器
+func NewJSONFileStore(dataFile string, syncInterval time.Duration) *JSONFileStore {
+	return &JSONFileStore{
+		appPorts:      make(map[string]int),
+		leases:        make(map[string]LeaseRecord),
+		pools:         make(map[string]PoolRecord),
+		dataFile:      dataFile,
+		syncInterval:  syncInterval,
+		stopChan:      make(chan struct{}),
+		leaseStopChan: make(chan struct{}),
+		subscribers:   make(map[chan Event]struct{}),
 	}
 }
 
 // LoadFromFile 从文件加载数据
-func (s *AppPortStorage) LoadFromFile() error {
+func (s *JSONFileStore) LoadFromFile() error {
 	// 确保目录存在
 	dir := filepath.Dir(s.dataFile)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -69,23 +98,41 @@ func (s *AppPortStorage) LoadFromFile() error {
 	if s.appPorts == nil {
 		s.appPorts = make(map[string]int)
 	}
+	// 旧版本数据文件（version < 1.1）没有leases字段，兼容处理为空map
+	s.leases = portData.Leases
+	if s.leases == nil {
+		s.leases = make(map[string]LeaseRecord)
+	}
+	// 旧版本数据文件（version < 1.2）没有pools字段，兼容处理为空map
+	s.pools = portData.Pools
+	if s.pools == nil {
+		s.pools = make(map[string]PoolRecord)
+	}
 	s.mu.Unlock()
 
 	return nil
 }
 
 // saveToFile 保存数据到文件
-func (s *AppPortStorage) saveToFile() error {
+func (s *JSONFileStore) saveToFile() error {
 	s.mu.RLock()
 	portData := PortData{
 		AppPorts:  make(map[string]int),
+		Leases:    make(map[string]LeaseRecord),
+		Pools:     make(map[string]PoolRecord),
 		Timestamp: time.Now().Unix(),
-		Version:   "1.0",
+		Version:   "1.2",
 	}
 	// 复制数据避免竞态条件
 	for k, v := range s.appPorts {
 		portData.AppPorts[k] = v
 	}
+	for k, v := range s.leases {
+		portData.Leases[k] = v
+	}
+	for k, v := range s.pools {
+		portData.Pools[k] = v
+	}
 	s.mu.RUnlock()
 
 	data, err := json.MarshalIndent(portData, "", "  ")
@@ -107,7 +154,7 @@ func (s *AppPortStorage) saveToFile() error {
 }
 
 // StartAutoSync 启动自动同步
-func (s *AppPortStorage) StartAutoSync() {
+func (s *JSONFileStore) StartAutoSync() {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
@@ -128,7 +175,7 @@ func (s *AppPortStorage) StartAutoSync() {
 }
 
 // StopAutoSync 停止自动同步
-func (s *AppPortStorage) StopAutoSync() {
+func (s *JSONFileStore) StopAutoSync() {
 	close(s.stopChan)
 	s.wg.Wait()
 	// 最后同步一次
@@ -136,7 +183,7 @@ func (s *AppPortStorage) StopAutoSync() {
 }
 
 // SetAppPort 设置APP端口
-func (s *AppPortStorage) SetAppPort(appName string, port int) error {
+func (s *JSONFileStore) SetAppPort(appName string, port int) error {
 	if appName == "" {
 		return fmt.Errorf("APP名称不能为空")
 	}
@@ -148,11 +195,13 @@ func (s *AppPortStorage) SetAppPort(appName string, port int) error {
 	s.appPorts[appName] = port
 	s.mu.Unlock()
 
+	s.broadcast(Event{AppName: appName, Port: port})
+
 	return nil
 }
 
 // GetAppPort 获取APP端口
-func (s *AppPortStorage) GetAppPort(appName string) (int, error) {
+func (s *JSONFileStore) GetAppPort(appName string) (int, error) {
 	if appName == "" {
 		return 0, fmt.Errorf("APP名称不能为空")
 	}
@@ -169,13 +218,13 @@ func (s *AppPortStorage) GetAppPort(appName string) (int, error) {
 }
 
 // DeleteAppPort 删除APP端口
-func (s *AppPortStorage) DeleteAppPort(appName string) error {
+func (s *JSONFileStore) DeleteAppPort(appName string) error {
 	if appName == "" {
 		return fmt.Errorf("APP名称不能为空")
 	}
 
 	s.mu.Lock()
-	_, exists := s.appPorts[appName]
+	port, exists := s.appPorts[appName]
 	if !exists {
 		s.mu.Unlock()
 		return fmt.Errorf("APP '%s' 不存在", appName)
@@ -183,12 +232,14 @@ func (s *AppPortStorage) DeleteAppPort(appName string) error {
 	delete(s.appPorts, appName)
 	s.mu.Unlock()
 
+	s.broadcast(Event{AppName: appName, Port: port, Deleted: true})
+
 	// 立即同步到文件
 	return s.saveToFile()
 }
 
 // HasApp 检查APP是否存在
-func (s *AppPortStorage) HasApp(appName string) bool {
+func (s *JSONFileStore) HasApp(appName string) bool {
 	s.mu.RLock()
 	_, exists := s.appPorts[appName]
 	s.mu.RUnlock()
@@ -196,7 +247,7 @@ func (s *AppPortStorage) HasApp(appName string) bool {
 }
 
 // GetAllApps 获取所有APP列表
-func (s *AppPortStorage) GetAllApps() map[string]int {
+func (s *JSONFileStore) GetAllApps() map[string]int {
 	s.mu.RLock()
 	result := make(map[string]int)
 	for k, v := range s.appPorts {
@@ -207,6 +258,254 @@ func (s *AppPortStorage) GetAllApps() map[string]int {
 }
 
 // SyncToFile 手动同步到文件
-func (s *AppPortStorage) SyncToFile() error {
+func (s *JSONFileStore) SyncToFile() error {
+	return s.saveToFile()
+}
+
+// CreateLease 创建一个端口租约并持久化
+func (s *JSONFileStore) CreateLease(appName string, port int, ttl time.Duration) (LeaseRecord, error) {
+	if appName == "" {
+		return LeaseRecord{}, fmt.Errorf("APP名称不能为空")
+	}
+	if ttl <= 0 {
+		return LeaseRecord{}, fmt.Errorf("租约有效期必须大于0")
+	}
+
+	record := LeaseRecord{
+		LeaseID:   fmt.Sprintf("lease-%s-%d", appName, time.Now().UnixNano()),
+		AppName:   appName,
+		Port:      port,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+
+	s.mu.Lock()
+	s.leases[record.LeaseID] = record
+	s.mu.Unlock()
+
+	return record, s.saveToFile()
+}
+
+// CreateLeaseRecord 持久化一条字段已全部确定好的租约记录，不生成LeaseID也不基于本地时钟计算过期时间。
+// 供集群模式下FSM在所有节点上确定性地回放同一条Raft日志调用。
+func (s *JSONFileStore) CreateLeaseRecord(record LeaseRecord) error {
+	if record.LeaseID == "" {
+		return fmt.Errorf("租约ID不能为空")
+	}
+	if record.AppName == "" {
+		return fmt.Errorf("APP名称不能为空")
+	}
+
+	s.mu.Lock()
+	s.leases[record.LeaseID] = record
+	s.mu.Unlock()
+
+	return s.saveToFile()
+}
+
+// RenewLease 续约一个已存在的端口租约，返回新的过期时间戳
+func (s *JSONFileStore) RenewLease(leaseID string, ttl time.Duration) (int64, error) {
+	if leaseID == "" {
+		return 0, fmt.Errorf("租约ID不能为空")
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("租约有效期必须大于0")
+	}
+
+	s.mu.Lock()
+	record, exists := s.leases[leaseID]
+	if !exists {
+		s.mu.Unlock()
+		return 0, fmt.Errorf("租约 '%s' 不存在", leaseID)
+	}
+	record.ExpiresAt = time.Now().Add(ttl).Unix()
+	s.leases[leaseID] = record
+	s.mu.Unlock()
+
+	return record.ExpiresAt, s.saveToFile()
+}
+
+// RenewLeaseTo 将租约续期到一个已确定好的绝对过期时间戳，用途同CreateLeaseRecord：
+// 供集群模式下FSM在所有节点上确定性地回放同一条Raft日志调用。
+func (s *JSONFileStore) RenewLeaseTo(leaseID string, expiresAt int64) error {
+	if leaseID == "" {
+		return fmt.Errorf("租约ID不能为空")
+	}
+
+	s.mu.Lock()
+	record, exists := s.leases[leaseID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("租约 '%s' 不存在", leaseID)
+	}
+	record.ExpiresAt = expiresAt
+	s.leases[leaseID] = record
+	s.mu.Unlock()
+
 	return s.saveToFile()
+}
+
+// ReleaseLease 释放一个端口租约，返回被释放的租约记录以便调用方归还端口
+func (s *JSONFileStore) ReleaseLease(leaseID string) (LeaseRecord, error) {
+	if leaseID == "" {
+		return LeaseRecord{}, fmt.Errorf("租约ID不能为空")
+	}
+
+	s.mu.Lock()
+	record, exists := s.leases[leaseID]
+	if !exists {
+		s.mu.Unlock()
+		return LeaseRecord{}, fmt.Errorf("租约 '%s' 不存在", leaseID)
+	}
+	delete(s.leases, leaseID)
+	s.mu.Unlock()
+
+	return record, s.saveToFile()
+}
+
+// GetLease 获取某个租约的信息
+func (s *JSONFileStore) GetLease(leaseID string) (LeaseRecord, error) {
+	s.mu.RLock()
+	record, exists := s.leases[leaseID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return LeaseRecord{}, fmt.Errorf("租约 '%s' 不存在", leaseID)
+	}
+	return record, nil
+}
+
+// ListLeases 获取当前所有租约
+func (s *JSONFileStore) ListLeases() []LeaseRecord {
+	s.mu.RLock()
+	result := make([]LeaseRecord, 0, len(s.leases))
+	for _, record := range s.leases {
+		result = append(result, record)
+	}
+	s.mu.RUnlock()
+	return result
+}
+
+// SetPool 创建或更新一个端口池定义并持久化
+func (s *JSONFileStore) SetPool(pool PoolRecord) error {
+	if pool.Name == "" {
+		return fmt.Errorf("pool名称不能为空")
+	}
+
+	s.mu.Lock()
+	s.pools[pool.Name] = pool
+	s.mu.Unlock()
+
+	return s.saveToFile()
+}
+
+// DeletePool 删除一个端口池定义并持久化
+func (s *JSONFileStore) DeletePool(name string) error {
+	s.mu.Lock()
+	if _, exists := s.pools[name]; !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("pool '%s' 不存在", name)
+	}
+	delete(s.pools, name)
+	s.mu.Unlock()
+
+	return s.saveToFile()
+}
+
+// ListPools 获取所有持久化的端口池定义
+func (s *JSONFileStore) ListPools() []PoolRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]PoolRecord, 0, len(s.pools))
+	for _, p := range s.pools {
+		result = append(result, p)
+	}
+	return result
+}
+
+// StartLeaseReaper 启动租约回收协程，定期扫描已过期的租约并通过onExpire归还端口
+func (s *JSONFileStore) StartLeaseReaper(interval time.Duration, onExpire func(appName string, port int)) {
+	s.leaseWg.Add(1)
+	go func() {
+		defer s.leaseWg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.reapExpiredLeases(onExpire)
+			case <-s.leaseStopChan:
+				return
+			}
+		}
+	}()
+}
+
+// StopLeaseReaper 停止租约回收协程
+func (s *JSONFileStore) StopLeaseReaper() {
+	close(s.leaseStopChan)
+	s.leaseWg.Wait()
+}
+
+// reapExpiredLeases 扫描并回收过期租约
+func (s *JSONFileStore) reapExpiredLeases(onExpire func(appName string, port int)) {
+	now := time.Now().Unix()
+
+	s.mu.Lock()
+	expired := make([]LeaseRecord, 0)
+	for leaseID, record := range s.leases {
+		if record.ExpiresAt <= now {
+			expired = append(expired, record)
+			delete(s.leases, leaseID)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(expired) == 0 {
+		return
+	}
+
+	if err := s.saveToFile(); err != nil {
+		fmt.Printf("租约回收后同步失败: %v\n", err)
+	}
+
+	for _, record := range expired {
+		if onExpire != nil {
+			onExpire(record.AppName, record.Port)
+		}
+	}
+}
+
+// Watch 订阅端口映射变化事件，返回的cancel函数用于取消订阅
+func (s *JSONFileStore) Watch() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	s.subMu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subMu.Unlock()
+
+	cancel := func() {
+		s.subMu.Lock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+		s.subMu.Unlock()
+	}
+
+	return ch, cancel
+}
+
+// broadcast 将变化事件推送给所有Watch订阅者
+func (s *JSONFileStore) broadcast(event Event) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者消费过慢，丢弃事件避免阻塞调用方
+		}
+	}
 }
\ No newline at end of file
@@ -0,0 +1,58 @@
+package storage
+
+import "time"
+
+// Event 描述一次端口映射变化，用于Watch/WatchAppPorts等订阅场景
+type Event struct {
+	AppName string // APP名称
+	Port    int    // 端口号
+	Deleted bool   // true表示该APP的端口映射被删除
+}
+
+// Store 端口存储后端的统一接口。JSONFileStore、BoltStore、EtcdStore均实现该接口，
+// PortServer只依赖该接口而不关心具体的持久化方式。
+type Store interface {
+	// SetAppPort 设置某个APP的端口
+	SetAppPort(appName string, port int) error
+	// GetAppPort 获取某个APP的端口
+	GetAppPort(appName string) (int, error)
+	// DeleteAppPort 删除某个APP的端口
+	DeleteAppPort(appName string) error
+	// HasApp 检查APP是否存在
+	HasApp(appName string) bool
+	// GetAllApps 列出当前所有APP端口映射
+	GetAllApps() map[string]int
+	// Watch 订阅端口映射变化事件，返回的cancel函数用于取消订阅
+	Watch() (<-chan Event, func())
+}
+
+// FileBackedStore 可选能力接口：需要显式从磁盘加载、并定期落盘的存储后端实现该接口。
+// JSONFileStore实现了该接口；BoltStore、EtcdStore各自管理自己的持久化，不实现该接口。
+type FileBackedStore interface {
+	LoadFromFile() error
+	StartAutoSync()
+	StopAutoSync()
+}
+
+// LeaseStore 可选能力接口：支持端口租约管理的存储后端实现该接口。JSONFileStore、BoltStore、EtcdStore均实现了该接口。
+type LeaseStore interface {
+	CreateLease(appName string, port int, ttl time.Duration) (LeaseRecord, error)
+	// CreateLeaseRecord 持久化一条字段已全部确定好的租约记录。区别于CreateLease，
+	// 它不生成LeaseID也不基于本地时钟计算过期时间，供集群模式下由FSM确定性回放Raft日志调用。
+	CreateLeaseRecord(record LeaseRecord) error
+	RenewLease(leaseID string, ttl time.Duration) (int64, error)
+	// RenewLeaseTo 将租约续期到一个已确定好的绝对过期时间戳，用途同CreateLeaseRecord。
+	RenewLeaseTo(leaseID string, expiresAt int64) error
+	ReleaseLease(leaseID string) (LeaseRecord, error)
+	GetLease(leaseID string) (LeaseRecord, error)
+	ListLeases() []LeaseRecord
+	StartLeaseReaper(interval time.Duration, onExpire func(appName string, port int))
+	StopLeaseReaper()
+}
+
+// PoolStore 可选能力接口：支持持久化端口池定义的存储后端实现该接口。JSONFileStore、BoltStore、EtcdStore均实现了该接口。
+type PoolStore interface {
+	SetPool(pool PoolRecord) error
+	DeletePool(name string) error
+	ListPools() []PoolRecord
+}